@@ -0,0 +1,7 @@
+resource "aws_instance" "main" {
+  instance_type = "t2.micro"
+}
+
+resource "aws_s3_bucket" "main" {
+  bucket = "example-corp-assets"
+}
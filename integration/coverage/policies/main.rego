@@ -0,0 +1,19 @@
+package tflint
+
+import rego.v1
+
+deny_legacy_instance_type contains issue if {
+	instances := terraform.resources("aws_instance", {"instance_type": "string"}, {})
+	instance_type := instances[_].config.instance_type
+	startswith(instance_type.value, "t2.")
+
+	issue := tflint.issue("t2 instance types are not allowed", instance_type.range)
+}
+
+deny_invalid_s3_bucket_name contains issue if {
+	buckets := terraform.resources("aws_s3_bucket", {"bucket": "string"}, {})
+	name := buckets[_].config.bucket
+	not startswith(name.value, "example-com-")
+
+	issue := tflint.issue(`Bucket names should always start with "example-com-"`, name.range)
+}
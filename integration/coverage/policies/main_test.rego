@@ -0,0 +1,13 @@
+package tflint
+
+import rego.v1
+
+# deny_invalid_s3_bucket_name is not tested, so the coverage is below the threshold
+test_deny_legacy_instance_type if {
+	issues := deny_legacy_instance_type with terraform.module as {"main.tf": `
+resource "aws_instance" "main" {
+  instance_type = "t2.micro"
+}`}
+
+	count(issues) == 1
+}
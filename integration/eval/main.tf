@@ -0,0 +1,11 @@
+locals {
+  name_prefix = "Example-Com-"
+}
+
+resource "aws_s3_bucket" "invalid" {
+  bucket = "example-corp-assets"
+}
+
+resource "aws_s3_bucket" "valid" {
+  bucket = "${lower(local.name_prefix)}assets"
+}
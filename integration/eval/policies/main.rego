@@ -0,0 +1,12 @@
+package tflint
+
+import rego.v1
+
+deny_invalid_s3_bucket_name contains issue if {
+	buckets := terraform.resources("aws_s3_bucket", {"bucket": "string"}, {})
+	name := buckets[_].config.bucket
+	prefix := terraform.eval("lower(local.name_prefix)")
+	not startswith(name.value, prefix.value)
+
+	issue := tflint.issue(sprintf("Bucket names should always start with %q", [prefix.value]), name.range)
+}
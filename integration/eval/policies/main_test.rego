@@ -0,0 +1,36 @@
+package tflint
+
+import rego.v1
+
+mock_sources := {"main.tf": `
+locals {
+  name_prefix = "Example-Com-"
+}
+
+resource "aws_s3_bucket" "invalid" {
+  bucket = "example-corp-assets"
+}
+
+resource "aws_s3_bucket" "valid" {
+  bucket = "${lower(local.name_prefix)}assets"
+}`}
+
+mock_resources(type, schema, options) := terraform.mock_resources(type, schema, options, mock_sources)
+
+mock_eval(src) := terraform.mock_eval(src, mock_sources)
+
+test_deny_invalid_s3_bucket_name_passed if {
+	issues := deny_invalid_s3_bucket_name with terraform.resources as mock_resources
+		with terraform.eval as mock_eval
+
+	count(issues) == 1
+	issue := issues[_]
+	issue.msg == `Bucket names should always start with "example-com-"`
+}
+
+test_deny_invalid_s3_bucket_name_failed if {
+	issues := deny_invalid_s3_bucket_name with terraform.resources as mock_resources
+		with terraform.eval as mock_eval
+
+	count(issues) == 0
+}
@@ -0,0 +1,7 @@
+variable "prefix" {
+  default = "example-corp-"
+}
+
+resource "aws_s3_bucket" "main" {
+  bucket = "${var.prefix}assets"
+}
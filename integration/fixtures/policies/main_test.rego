@@ -0,0 +1,23 @@
+package tflint
+
+import rego.v1
+
+test_deny_invalid_s3_bucket_name_passed if {
+	issues := deny_invalid_s3_bucket_name with terraform.module as terraform.fixture("fixtures/invalid_bucket")
+
+	count(issues) == 1
+	issue := issues[_]
+	issue.msg == `Bucket names should always start with "example-com-"`
+}
+
+test_deny_invalid_s3_bucket_name_failed if {
+	issues := deny_invalid_s3_bucket_name with terraform.module as terraform.fixture("fixtures/valid_bucket")
+
+	count(issues) == 1
+}
+
+test_deny_invalid_s3_bucket_name_errored if {
+	issues := deny_invalid_s3_bucket_name with terraform.module as terraform.fixture("fixtures/missing_bucket")
+
+	count(issues) == 0
+}
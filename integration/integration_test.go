@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -42,12 +43,16 @@ type Pos struct {
 	Column int `json:"column"`
 }
 
+// durationRegexp matches test durations in messages such as "test failed: data.tflint.test_deny (1.024ms)"
+var durationRegexp = regexp.MustCompile(`\(\d+(\.\d+)?(ns|µs|ms|s)\)`)
+
 func TestIntegration(t *testing.T) {
 	tests := []struct {
 		name    string
 		command *exec.Cmd
 		dir     string
 		test    bool
+		env     []string
 	}{
 		{
 			name:    "instance type",
@@ -269,6 +274,52 @@ func TestIntegration(t *testing.T) {
 			dir:     "actions",
 			test:    true,
 		},
+		{
+			name:    "eval",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "eval",
+		},
+		{
+			name:    "eval (test)",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "eval",
+			test:    true,
+		},
+		{
+			name:    "module mock",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "module_mock",
+		},
+		{
+			name:    "module mock (test)",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "module_mock",
+			test:    true,
+		},
+		{
+			name:    "fixtures",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "fixtures",
+		},
+		{
+			name:    "fixtures (test)",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "fixtures",
+			test:    true,
+		},
+		{
+			name:    "coverage",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "coverage",
+			env:     []string{"TFLINT_OPA_COVERAGE_THRESHOLD=90"},
+		},
+		{
+			name:    "coverage (test)",
+			command: exec.Command("tflint", "--format", "json", "--force"),
+			dir:     "coverage",
+			test:    true,
+			env:     []string{"TFLINT_OPA_COVERAGE_THRESHOLD=90"},
+		},
 	}
 
 	dir, _ := os.Getwd()
@@ -279,8 +330,9 @@ func TestIntegration(t *testing.T) {
 			testDir := filepath.Join(dir, test.dir)
 			t.Chdir(testDir)
 
+			test.command.Env = append(os.Environ(), test.env...)
 			if test.test {
-				test.command.Env = append(os.Environ(), "TFLINT_OPA_TEST=1")
+				test.command.Env = append(test.command.Env, "TFLINT_OPA_TEST=1")
 			}
 
 			var stdout, stderr bytes.Buffer
@@ -312,8 +364,9 @@ func TestIntegration(t *testing.T) {
 					}
 					return a.Rule.Name > b.Rule.Name
 				}),
-				cmpopts.AcyclicTransformer("Link", func(path string) string {
-					return filepath.ToSlash(path)
+				// Paths are compared with forward slashes, and test durations are ignored
+				cmpopts.AcyclicTransformer("Normalize", func(s string) string {
+					return durationRegexp.ReplaceAllString(filepath.ToSlash(s), "(0s)")
 				}),
 			}
 			if diff := cmp.Diff(want, got, opts...); diff != "" {
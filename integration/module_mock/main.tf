@@ -0,0 +1,11 @@
+locals {
+  allowed_instance_types = ["t3.micro", "t3.small"]
+}
+
+resource "aws_instance" "invalid" {
+  instance_type = "t2.micro"
+}
+
+resource "aws_instance" "valid" {
+  instance_type = "t3.micro"
+}
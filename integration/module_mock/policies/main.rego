@@ -0,0 +1,12 @@
+package tflint
+
+import rego.v1
+
+deny_not_allowed_instance_type contains issue if {
+	allowed := terraform.eval("local.allowed_instance_types")
+	instances := terraform.resources("aws_instance", {"instance_type": "string"}, {})
+	instance_type := instances[_].config.instance_type
+	not instance_type.value in allowed.value
+
+	issue := tflint.issue(sprintf("%s is not allowed", [instance_type.value]), instance_type.range)
+}
@@ -0,0 +1,31 @@
+package tflint
+
+import rego.v1
+
+test_deny_not_allowed_instance_type_passed if {
+	issues := deny_not_allowed_instance_type with terraform.module as {"main.tf": `
+locals {
+  allowed_instance_types = ["t3.micro"]
+}
+
+resource "aws_instance" "main" {
+  instance_type = "t2.micro"
+}`}
+
+	count(issues) == 1
+	issue := issues[_]
+	issue.msg == "t2.micro is not allowed"
+}
+
+test_deny_not_allowed_instance_type_failed if {
+	issues := deny_not_allowed_instance_type with terraform.module as {"main.tf": `
+locals {
+  allowed_instance_types = ["t3.micro"]
+}
+
+resource "aws_instance" "main" {
+  instance_type = "t2.micro"
+}`}
+
+	count(issues) == 0
+}
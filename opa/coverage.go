@@ -0,0 +1,174 @@
+package opa
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/open-policy-agent/opa/v1/cover"
+	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
+)
+
+// coverageReporter reports the coverage of tests accumulated in the engine.
+// It is not a rule defined by policies and is not registered in the ruleset,
+// but it satisfies tflint.Rule so that the ruleset can run it after all enabled
+// test rules, since the SDK has no hook to run after the rules.
+type coverageReporter struct {
+	tflint.DefaultRule
+
+	engine    *Engine
+	policyDir string
+
+	dir       string
+	threshold float64
+
+	// Check is called for each module when module calls are inspected,
+	// but the coverage is reported only once per run.
+	once sync.Once
+}
+
+var _ tflint.Rule = (*coverageReporter)(nil)
+
+// newCoverageReporter returns a reporter that writes the coverage report to the directory
+// and emits an issue if the coverage is below the threshold.
+// The report is not written if the directory is empty, and the threshold is
+// not checked if it is zero.
+func newCoverageReporter(engine *Engine, policyDir string, dir string, threshold float64) *coverageReporter {
+	engine.EnableCoverage()
+
+	return &coverageReporter{
+		engine:    engine,
+		policyDir: policyDir,
+		dir:       dir,
+		threshold: threshold,
+	}
+}
+
+func (r *coverageReporter) Name() string {
+	return "opa_coverage"
+}
+
+func (r *coverageReporter) Enabled() bool {
+	return true
+}
+
+func (r *coverageReporter) Severity() tflint.Severity {
+	// Severity is always error
+	return tflint.ERROR
+}
+
+func (r *coverageReporter) Check(runner tflint.Runner) error {
+	var err error
+	r.once.Do(func() { err = r.check(runner) })
+	return err
+}
+
+func (r *coverageReporter) check(runner tflint.Runner) error {
+	report := r.engine.CoverageReport()
+
+	if r.dir != "" {
+		if err := writeCoverageReport(r.dir, report); err != nil {
+			return fmt.Errorf("failed to write the coverage report; %w", err)
+		}
+	}
+
+	if r.threshold > 0 && report.Coverage < r.threshold {
+		return runner.EmitIssue(
+			r,
+			fmt.Sprintf("test coverage %.2f%% is below the threshold %.2f%%", report.Coverage, r.threshold),
+			r.issueRange(report),
+		)
+	}
+
+	return nil
+}
+
+// issueRange returns the first uncovered line of the first uncovered file in the report.
+// If there are no uncovered lines, it returns the policy directory.
+func (r *coverageReporter) issueRange(report cover.Report) hcl.Range {
+	files := make([]string, 0, len(report.Files))
+	for file, fr := range report.Files {
+		if len(fr.NotCovered) > 0 {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return hcl.Range{Filename: r.policyDir}
+	}
+	sort.Strings(files)
+
+	row := report.Files[files[0]].NotCovered[0].Start.Row
+	for _, rng := range report.Files[files[0]].NotCovered {
+		row = min(row, rng.Start.Row)
+	}
+	return hcl.Range{
+		Filename: files[0],
+		Start:    hcl.Pos{Line: row, Column: 1},
+		End:      hcl.Pos{Line: row, Column: 1},
+	}
+}
+
+// writeCoverageReport writes the coverage report to the directory
+// as coverage.json (the same format as "opa test --coverage") and lcov.info.
+func writeCoverageReport(dir string, report cover.Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "coverage.json"), out, 0o644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, "lcov.info"), []byte(lcovReport(report)), 0o644)
+}
+
+// lcovReport returns the coverage report in the LCOV format.
+// Covered lines have a hit count of 1, and uncovered lines have 0.
+func lcovReport(report cover.Report) string {
+	files := make([]string, 0, len(report.Files))
+	for file := range report.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, file := range files {
+		fr := report.Files[file]
+
+		lines := map[int]int{}
+		for _, rng := range fr.NotCovered {
+			for row := rng.Start.Row; row <= rng.End.Row; row++ {
+				lines[row] = 0
+			}
+		}
+		for _, rng := range fr.Covered {
+			for row := rng.Start.Row; row <= rng.End.Row; row++ {
+				lines[row] = 1
+			}
+		}
+		rows := make([]int, 0, len(lines))
+		for row := range lines {
+			rows = append(rows, row)
+		}
+		sort.Ints(rows)
+
+		fmt.Fprintf(&b, "TN:\nSF:%s\n", file)
+		hit := 0
+		for _, row := range rows {
+			fmt.Fprintf(&b, "DA:%d,%d\n", row, lines[row])
+			hit += lines[row]
+		}
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\nend_of_record\n", len(rows), hit)
+	}
+
+	return b.String()
+}
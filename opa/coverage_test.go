@@ -0,0 +1,135 @@
+package opa
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/liamg/memoryfs"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/terraform-linters/tflint-plugin-sdk/helper"
+)
+
+func TestCoverageReporter_Check(t *testing.T) {
+	fs := memoryfs.New()
+	policy := `
+package tflint
+
+import rego.v1
+
+deny_foo contains issue if {
+	terraform.resources("foo", {}, {})[_]
+	issue := tflint.issue("foo", terraform.module_range())
+}
+
+deny_bar contains issue if {
+	terraform.resources("bar", {}, {})[_]
+	issue := tflint.issue("bar", terraform.module_range())
+}`
+	test := `
+package tflint
+
+import rego.v1
+
+test_deny_foo if {
+	issues := deny_foo with terraform.module as {"main.tf": ` + "`" + `resource "foo" "main" {}` + "`" + `}
+
+	count(issues) == 1
+}`
+	fs.WriteFile("main.rego", []byte(policy), 0o644)
+	fs.WriteFile("main_test.rego", []byte(test), 0o644)
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      helper.Issues
+	}{
+		{
+			name:      "below the threshold",
+			threshold: 80,
+			want: helper.Issues{
+				{
+					Rule:    &coverageReporter{},
+					Message: "test coverage 66.67% is below the threshold 80.00%",
+					Range: hcl.Range{
+						Filename: "main.rego",
+						Start:    hcl.Pos{Line: 11, Column: 1},
+						End:      hcl.Pos{Line: 11, Column: 1},
+					},
+				},
+			},
+		},
+		{
+			name:      "above the threshold",
+			threshold: 60,
+			want:      helper.Issues{},
+		},
+		{
+			name:      "no threshold",
+			threshold: 0,
+			want:      helper.Issues{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ret, err := loader.NewFileLoader().WithFS(fs).Filtered([]string{"."}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine, err := NewEngine(ret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Join(t.TempDir(), "coverage")
+			reporter := newCoverageReporter(engine, ".", dir, test.threshold)
+
+			runner := helper.TestRunner(t, map[string]string{})
+			if err := NewTestRule(&ast.Rule{Head: &ast.Head{Name: "test_deny_foo"}}, engine).Check(runner); err != nil {
+				t.Fatal(err)
+			}
+			// Check is called for each module, but the coverage is reported only once
+			for range 2 {
+				if err := reporter.Check(runner); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			helper.AssertIssues(t, test.want, runner.Issues)
+
+			if _, err := os.Stat(filepath.Join(dir, "coverage.json")); err != nil {
+				t.Error(err)
+			}
+			lcov, err := os.ReadFile(filepath.Join(dir, "lcov.info"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := `TN:
+SF:main.rego
+DA:6,1
+DA:7,1
+DA:8,1
+DA:11,0
+DA:12,0
+DA:13,0
+LF:6
+LH:3
+end_of_record
+TN:
+SF:main_test.rego
+DA:6,1
+DA:7,1
+DA:9,1
+LF:3
+LH:3
+end_of_record
+`
+			if diff := cmp.Diff(want, string(lcov)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
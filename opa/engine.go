@@ -1,14 +1,18 @@
 package opa
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/cover"
 	"github.com/open-policy-agent/opa/v1/loader"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/storage"
@@ -24,11 +28,15 @@ import (
 // Engine evaluates policies and returns issues.
 // In other words, this is a wrapper of rego.New(...).Eval().
 type Engine struct {
-	store       storage.Store
-	modules     map[string]*ast.Module
-	print       print.Hook
-	traceWriter io.Writer
-	runtime     *ast.Term
+	store   storage.Store
+	modules map[string]*ast.Module
+	// mockedModules are modules in which `with terraform.module as {...}` is rewritten.
+	// They are evaluated instead of the loaded modules, which are used for coverage reports.
+	mockedModules map[string]*ast.Module
+	print         print.Hook
+	traceWriter   io.Writer
+	runtime       *ast.Term
+	coverage      *cover.Cover
 }
 
 // NewEngine returns a new engine based on the policies loaded
@@ -48,12 +56,21 @@ func NewEngine(ret *loader.Result) (*Engine, error) {
 		traceWriter = logWriter
 	}
 
+	modules := ret.ParsedModules()
+	// The rewrite is done once here, as RunQuery and RunTest are called for each rule.
+	// Test modules are also compiled by RunQuery, so both use the rewritten modules.
+	mockedModules, err := withModuleMocks(modules)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Engine{
-		store:       store,
-		modules:     ret.ParsedModules(),
-		print:       printer,
-		traceWriter: traceWriter,
-		runtime:     runtime(),
+		store:         store,
+		modules:       modules,
+		mockedModules: mockedModules,
+		print:         printer,
+		traceWriter:   traceWriter,
+		runtime:       runtime(),
 	}, nil
 }
 
@@ -96,7 +113,7 @@ func (e *Engine) RunQuery(rule *Rule, runner tflint.Runner) ([]*funcs.Issue, err
 		rego.Runtime(e.runtime),
 	}
 	// Set policies
-	for _, m := range e.modules {
+	for _, m := range e.mockedModules {
 		options = append(options, rego.ParsedModule(m))
 	}
 	// Enable custom functions (e.g. terraform.resources)
@@ -138,6 +155,7 @@ func (e *Engine) RunQuery(rule *Rule, runner tflint.Runner) ([]*funcs.Issue, err
 
 // RunTest runs a policy test. The details are hidden inside open-policy-agent/opa/tester
 // and this is a wrapper of it. Test results are emitted as issues if failed or errored.
+// The issue range is the location of the test in the Rego file.
 //
 // A runner is provided, but in many cases the runner is never actually used,
 // as test runners are generated inside mock functions. See TesterMockFunctions for details.
@@ -149,9 +167,12 @@ func (e *Engine) RunTest(rule *TestRule, runner tflint.Runner) ([]*funcs.Issue,
 		CapturePrintOutput(true).
 		EnableTracing(traceEnabled).
 		SetRuntime(e.runtime).
-		SetModules(e.modules).
+		SetModules(e.mockedModules).
 		AddCustomBuiltins(append(TesterFunctions(runner), TesterMockFunctions()...)).
 		Filter(rule.RegoName())
+	if e.coverage != nil {
+		testRunner.SetCoverageQueryTracer(e.coverage)
+	}
 
 	ch, err := testRunner.RunTests(context.Background(), nil)
 	if err != nil {
@@ -160,14 +181,6 @@ func (e *Engine) RunTest(rule *TestRule, runner tflint.Runner) ([]*funcs.Issue,
 
 	var issues []*funcs.Issue
 	for ret := range ch {
-		if ret.Error != nil {
-			// Location is not included as it is not an issue for HCL.
-			issues = append(issues, &funcs.Issue{
-				Message: fmt.Sprintf("test errored: %s", ret.Error),
-			})
-			continue
-		}
-
 		if ret.Output != nil {
 			logger.Debug(string(ret.Output))
 		}
@@ -175,16 +188,75 @@ func (e *Engine) RunTest(rule *TestRule, runner tflint.Runner) ([]*funcs.Issue,
 			topdown.PrettyTrace(e.traceWriter, ret.Trace)
 		}
 
-		if ret.Fail {
-			issues = append(issues, &funcs.Issue{
-				Message: "test failed",
-			})
+		if ret.Pass() || ret.Skip {
+			continue
 		}
+		issues = append(issues, &funcs.Issue{
+			Message: testResultMessage(ret),
+			Range:   testResultRange(ret),
+		})
 	}
 
 	return issues, nil
 }
 
+// EnableCoverage enables coverage tracing in RunTest.
+// Coverage is accumulated across all tests run by the engine.
+func (e *Engine) EnableCoverage() {
+	e.coverage = cover.New()
+}
+
+// CoverageReport returns the coverage report of the tests run so far.
+// Generated modules (e.g. module mocks) are not included.
+func (e *Engine) CoverageReport() cover.Report {
+	if e.coverage == nil {
+		return cover.Report{}
+	}
+	return e.coverage.Report(e.modules)
+}
+
+// testResultMessage returns a message of the failed or errored test result
+// like "opa test -v". Print output is also included.
+func testResultMessage(ret *tester.Result) string {
+	var b strings.Builder
+
+	duration := ret.Duration.Round(time.Microsecond)
+	if ret.Error != nil {
+		fmt.Fprintf(&b, "test errored: %s.%s (%s): %s", ret.Package, ret.Name, duration, ret.Error)
+	} else {
+		fmt.Fprintf(&b, "test failed: %s.%s (%s)", ret.Package, ret.Name, duration)
+		if ret.FailedAt != nil && ret.FailedAt.Location != nil {
+			fmt.Fprintf(&b, "\n\nfailed at %s: %s", ret.FailedAt.Location, ret.FailedAt)
+		}
+	}
+
+	if output := strings.TrimSpace(string(ret.Output)); output != "" {
+		fmt.Fprintf(&b, "\n\nprint output:\n%s", output)
+	}
+
+	return b.String()
+}
+
+// testResultRange returns the range of the test rule in the Rego file.
+// The range covers the first line of the rule.
+func testResultRange(ret *tester.Result) hcl.Range {
+	loc := ret.Location
+	if loc == nil {
+		return hcl.Range{}
+	}
+
+	text := loc.Text
+	if i := bytes.IndexByte(text, '\n'); i >= 0 {
+		text = bytes.TrimSuffix(text[:i], []byte("\r"))
+	}
+
+	return hcl.Range{
+		Filename: loc.File,
+		Start:    hcl.Pos{Line: loc.Row, Column: loc.Col, Byte: loc.Offset},
+		End:      hcl.Pos{Line: loc.Row, Column: loc.Col + len(text), Byte: loc.Offset + len(text)},
+	}
+}
+
 func runtime() *ast.Term {
 	env := ast.NewObject()
 	for _, pair := range os.Environ() {
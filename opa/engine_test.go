@@ -2,6 +2,7 @@ package opa
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -51,6 +52,66 @@ deny_test contains issue if {
 			},
 			want: []*funcs.Issue{{Message: "data.foo is bar", Range: hcl.Range{Filename: "main.tf", Start: hcl.InitialPos, End: hcl.InitialPos}}},
 		},
+		{
+			name: "partially unknown values halt evaluation",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {"tags": "map(string)"}, {})
+	tags := resources[_].config.tags
+
+	tags.value.Owner != "platform"
+
+	issue := tflint.issue("Owner must be platform", tags.range)
+}`,
+			},
+			config: map[string]string{
+				"main.tf": `
+variable "owner" {}
+
+resource "aws_instance" "main" {
+	tags = {
+		Name  = "main"
+		Owner = var.owner
+	}
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "partial values are opt-in",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {"tags": "partial(map(string))"}, {})
+	tags := resources[_].config.tags
+
+	not "Team" in object.keys(tags.value)
+
+	issue := tflint.issue("Team tag is required", tags.range)
+}`,
+			},
+			config: map[string]string{
+				"main.tf": `
+variable "owner" {}
+
+resource "aws_instance" "main" {
+	tags = {
+		Name  = "main"
+		Owner = var.owner
+	}
+}`,
+			},
+			want: []*funcs.Issue{{Message: "Team tag is required", Range: hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 5}, End: hcl.Pos{Line: 8}}}},
+		},
 		{
 			name: "terraform functions",
 			policies: map[string]string{
@@ -76,6 +137,41 @@ resource "aws_instance" "main" {
 			},
 			want: []*funcs.Issue{{Message: "t2.micro is only allowed", Range: hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 3}, End: hcl.Pos{Line: 3}}}},
 		},
+		{
+			name: "eval",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {}, {})
+	resource := resources[_]
+
+	tags := terraform.eval("merge(local.tags, { Name = var.name })")
+	not tags.value.Owner
+	name := terraform.eval_at("var.name", resource.decl_range)
+
+	issue := tflint.issue(sprintf("Owner tag is required in %s", [name.value]), name.range)
+}`,
+			},
+			config: map[string]string{
+				"main.tf": `
+variable "name" {
+  default = "main"
+}
+
+locals {
+  tags = { Env = "prod" }
+}
+
+resource "aws_instance" "main" {
+  tags = merge(local.tags, { Name = var.name })
+}`,
+			},
+			want: []*funcs.Issue{{Message: "Owner tag is required in main", Range: hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 10}, End: hcl.Pos{Line: 10}}}},
+		},
 		{
 			name: "runtime",
 			policies: map[string]string{
@@ -121,6 +217,43 @@ deny_test contains issue if {
 			},
 			err: "main.rego:7: eval_builtin_error: terraform.resources: unknown option: unknown",
 		},
+		{
+			name: "test-only function",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	count(terraform.fixture("fixtures/main")) > 0
+	issue := tflint.issue("fixture is loaded", terraform.module_range())
+}`,
+			},
+			err: "main.rego:7: eval_builtin_error: terraform.fixture: terraform.fixture is only available in tests",
+		},
+		{
+			name: "test files using test-only functions",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	issue := tflint.issue("example issue", terraform.module_range())
+}`,
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	count(deny_test) == 1 with terraform.module as terraform.fixture("fixtures/main")
+}`,
+			},
+			want: []*funcs.Issue{{Message: "example issue", Range: hcl.Range{Filename: "main.tf", Start: hcl.InitialPos, End: hcl.InitialPos}}},
+		},
 		{
 			name: "invalid issue",
 			policies: map[string]string{
@@ -197,7 +330,7 @@ test_deny if {
 	"foo" == "bar"
 }`,
 			},
-			want: []*funcs.Issue{{Message: "test failed"}},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
 		},
 		{
 			name: "store data",
@@ -212,7 +345,7 @@ test_deny if {
 }`,
 				"data.yaml": `foo: bar`,
 			},
-			want: []*funcs.Issue{{Message: "test failed"}},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
 		},
 		{
 			name: "terraform functions",
@@ -245,7 +378,238 @@ test_deny if {
 }
 				`,
 			},
-			want: []*funcs.Issue{{Message: "test failed"}},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 11}, End: hcl.Pos{Line: 11}}}},
+		},
+		{
+			name: "mock files",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	files := terraform.mock_files({"main.tf": "", "README.md": "# README"})
+	count(files) == 1
+	terraform.mock_read_file("README.md", {"main.tf": "", "README.md": "# README"}) == "# README"
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "mock lockfile",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	providers := terraform.mock_lockfile({".terraform.lock.hcl": ` + "`" + `
+provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.0.0"
+  hashes  = ["h1:abc="]
+}` + "`" + `})
+	providers[0].version.value == "5.0.0"
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "module fixture",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {"instance_type": "string"}, {})
+	instance_type := resources[_].config.instance_type
+
+	variables := terraform.variables({"default": "string"}, {})
+	allowed := variables[_].config.default
+
+	instance_type.value != allowed.value
+
+	issue := tflint.issue("instance type is not allowed", terraform.module_range())
+}`,
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	count(deny_test) == 1 with terraform.module as {"main.tf": ` + "`" + `
+variable "allowed" {
+	default = "t2.micro"
+}
+
+resource "aws_instance" "main" {
+	instance_type = "t1.micro"
+}` + "`" + `}
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "module fixture with explicit mocks",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {"instance_type": "string"}, {})
+	instance_type := resources[_].config.instance_type
+
+	some local in terraform.locals({})
+	local.name == "allowed"
+	instance_type.value != local.expr.value
+
+	issue := tflint.issue("instance type is not allowed", instance_type.range)
+}`,
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+mock_resources(type, schema, options) := terraform.mock_resources(type, schema, options, {"main.tf": ` + "`" + `
+resource "aws_instance" "main" {
+	instance_type = "t2.micro"
+}` + "`" + `})
+
+test_deny if {
+	count(deny_test) == 0 with terraform.module as {"main.tf": ` + "`" + `
+locals {
+	allowed = "t2.micro"
+}
+
+resource "aws_instance" "main" {
+	instance_type = "t1.micro"
+}` + "`" + `}
+		with terraform.resources as mock_resources
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "mock with inputs",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	resources := terraform.mock_resources("aws_instance", {"instance_type": "string", "ami": "string"}, {}, {"main.tf": ` + "`" + `
+variable "instance_type" {}
+variable "ami" {}
+
+resource "aws_instance" "main" {
+	instance_type = var.instance_type
+	ami           = var.ami
+}` + "`" + `, "__inputs__": {"variables": {"instance_type": "t2.micro"}, "unknown_variables": ["ami"]}})
+	resources[0].config.instance_type.value == "t2.micro"
+	resources[0].config.ami.unknown
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "module fixture with inputs",
+			policies: map[string]string{
+				"main.rego": `
+package tflint
+
+import rego.v1
+
+deny_test contains issue if {
+	resources := terraform.resources("aws_instance", {"instance_type": "string"}, {})
+	instance_type := resources[_].config.instance_type
+
+	instance_type.value != "t2.micro"
+
+	issue := tflint.issue("instance type is not allowed", instance_type.range)
+}`,
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+fixture := {"main.tf": ` + "`" + `
+variable "instance_type" {
+	default = "t2.micro"
+}
+
+resource "aws_instance" "main" {
+	instance_type = var.instance_type
+}` + "`" + `}
+
+test_deny if {
+	count(deny_test) == 0 with terraform.module as fixture
+	count(deny_test) == 1 with terraform.module as object.union(fixture, {"__inputs__": {"variables": {"instance_type": "t1.micro"}}})
+}`,
+			},
+			want: nil,
+		},
+		{
+			name: "mock with invalid source",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	count(terraform.mock_files({"main.tf": 1})) == 1
+}`,
+			},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
+		},
+		{
+			name: "missing fixture",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	count(terraform.mock_files(terraform.fixture("fixtures/missing"))) == 1
+}`,
+			},
+			want: []*funcs.Issue{{Message: "test errored: data.tflint.test_deny (0s): main_test.rego:7: eval_builtin_error: terraform.fixture: fixture directory fixtures/missing does not exist", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
+		},
+		{
+			name: "mock function error",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	count(terraform.mock_files({"main.tf": "resource {"})) == 1
+}`,
+			},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
+		},
+		{
+			name: "print output",
+			policies: map[string]string{
+				"main_test.rego": `
+package tflint
+
+import rego.v1
+
+test_deny if {
+	print("foo is", "bar")
+	"foo" == "bar"
+}`,
+			},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)\n\nprint output:\nfoo is bar", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
 		},
 		{
 			name: "runtime",
@@ -259,7 +623,7 @@ test_deny if {
 	"foo" == opa.runtime().version
 }`,
 			},
-			want: []*funcs.Issue{{Message: "test failed"}},
+			want: []*funcs.Issue{{Message: "test failed: data.tflint.test_deny (0s)", Range: hcl.Range{Filename: "main_test.rego", Start: hcl.Pos{Line: 6}, End: hcl.Pos{Line: 6}}}},
 		},
 	}
 
@@ -296,10 +660,20 @@ test_deny if {
 				t.Fatal("should return an error, but it does not")
 			}
 
-			opt := cmpopts.IgnoreFields(hcl.Pos{}, "Column", "Byte")
-			if diff := cmp.Diff(test.want, got, opt); diff != "" {
+			opts := []cmp.Option{
+				cmpopts.IgnoreFields(hcl.Pos{}, "Column", "Byte"),
+				cmp.Transformer("normalizeDuration", normalizeDuration),
+			}
+			if diff := cmp.Diff(test.want, got, opts...); diff != "" {
 				t.Error(diff)
 			}
 		})
 	}
 }
+
+var durationRe = regexp.MustCompile(`\(([0-9.]+[a-zµ]+)+\)`)
+
+// normalizeDuration replaces test durations in messages with a fixed value.
+func normalizeDuration(msg string) string {
+	return durationRe.ReplaceAllString(msg, "(0s)")
+}
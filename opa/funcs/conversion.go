@@ -1,18 +1,29 @@
 package funcs
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/open-policy-agent/opa/v1/types"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
+	"github.com/terraform-linters/tflint-plugin-sdk/terraform/addrs"
 	"github.com/terraform-linters/tflint-plugin-sdk/terraform/lang/marks"
 	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
+	"github.com/terraform-linters/tflint-ruleset-opa/opa/tester"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/convert"
 	ctyjson "github.com/zclconf/go-cty/cty/json"
@@ -29,27 +40,101 @@ var schemaTy = types.NewObject(
 // It is not intended as a general capsule type in the cty type system, but acts as the identifier for the keyword.
 var exprCty cty.Type = cty.Capsule("expr", reflect.TypeOf((*hcl.Expression)(nil)))
 
+// exprAndValue is the encapsulated type of capsule types corresponding to "expr_and_value" in the extended schema type syntax.
+// The type of the value is stored as extension data of the capsule type.
+type exprAndValue struct{}
+
+type exprAndValueExtensionKey struct{}
+
+func exprAndValueCty(ty cty.Type) cty.Type {
+	return cty.CapsuleWithOps("expr_and_value", reflect.TypeOf(exprAndValue{}), &cty.CapsuleOps{
+		TypeGoString: func(_ reflect.Type) string {
+			return fmt.Sprintf("exprAndValueCty(%#v)", ty)
+		},
+		ExtensionData: func(key any) any {
+			if key == (exprAndValueExtensionKey{}) {
+				return ty
+			}
+			return nil
+		},
+	})
+}
+
+// exprAndValueType returns the type of the value if the given type is "expr_and_value".
+func exprAndValueType(ty cty.Type) (cty.Type, bool) {
+	if !ty.IsCapsuleType() || ty.EncapsulatedType() != reflect.TypeOf(exprAndValue{}) {
+		return cty.NilType, false
+	}
+	return ty.CapsuleExtensionData(exprAndValueExtensionKey{}).(cty.Type), true
+}
+
+// partialValueType is the encapsulated type of capsule types corresponding to "partial" in the extended schema type syntax.
+// The type of the value is stored as extension data of the capsule type.
+type partialValueType struct{}
+
+type partialValueExtensionKey struct{}
+
+func partialCty(ty cty.Type) cty.Type {
+	return cty.CapsuleWithOps("partial", reflect.TypeOf(partialValueType{}), &cty.CapsuleOps{
+		TypeGoString: func(_ reflect.Type) string {
+			return fmt.Sprintf("partialCty(%#v)", ty)
+		},
+		ExtensionData: func(key any) any {
+			if key == (partialValueExtensionKey{}) {
+				return ty
+			}
+			return nil
+		},
+	})
+}
+
+// partialType returns the type of the value if the given type is "partial".
+func partialType(ty cty.Type) (cty.Type, bool) {
+	if !ty.IsCapsuleType() || ty.EncapsulatedType() != reflect.TypeOf(partialValueType{}) {
+		return cty.NilType, false
+	}
+	return ty.CapsuleExtensionData(partialValueExtensionKey{}).(cty.Type), true
+}
+
+// nestedBlockCty marks paths of nested blocks in the type map.
+// This is not a type of attributes, but is used to determine whether a block is declared in the schema.
+var nestedBlockCty cty.Type = cty.Capsule("nested_block", reflect.TypeOf((*hclext.Block)(nil)))
+
+// dynamicBlockCty marks paths of dynamic blocks that are added to the schema implicitly.
+var dynamicBlockCty cty.Type = cty.Capsule("dynamic_block", reflect.TypeOf((*hclext.Block)(nil)))
+
 func jsonToSchema(in map[string]any, tyMap map[string]cty.Type, path string) (*hclext.BodySchema, map[string]cty.Type, error) {
 	schema := &hclext.BodySchema{}
 
+	// "__just_attributes" is a special key that retrieves all attributes in the body
+	// with the given type, without declaring their names.
+	if v, exists := in["__just_attributes"]; exists {
+		key := fmt.Sprintf("%s.__just_attributes", path)
+		if len(in) > 1 {
+			return schema, tyMap, fmt.Errorf("%s cannot be used with other keys", key)
+		}
+		cv, ok := v.(string)
+		if !ok {
+			return schema, tyMap, fmt.Errorf("%s is not string, got %T", key, v)
+		}
+		ty, err := jsonToSchemaType(cv, key)
+		if err != nil {
+			return schema, tyMap, err
+		}
+		tyMap[fmt.Sprintf("%s.*", path)] = ty
+
+		schema.Mode = hclext.SchemaJustAttributesMode
+		return schema, tyMap, nil
+	}
+
 	for k, v := range in {
 		key := fmt.Sprintf("%s.%s", path, k)
 
 		switch cv := v.(type) {
 		case string:
-			var ty cty.Type
-			if cv == "expr" {
-				// "expr" is a special type that allows you to get the raw expression without evaluating it.
-				ty = exprCty
-			} else {
-				expr, diags := hclsyntax.ParseExpression([]byte(cv), "", hcl.InitialPos)
-				if diags.HasErrors() {
-					return schema, tyMap, fmt.Errorf("type expr parse error in %s; %s", key, withoutSubject(diags))
-				}
-				ty, diags = typeexpr.TypeConstraint(expr)
-				if diags.HasErrors() {
-					return schema, tyMap, fmt.Errorf("type constraint parse error in %s; %s", key, withoutSubject(diags))
-				}
+			ty, err := jsonToSchemaType(cv, key)
+			if err != nil {
+				return schema, tyMap, err
 			}
 			tyMap[key] = ty
 
@@ -79,6 +164,7 @@ func jsonToSchema(in map[string]any, tyMap map[string]cty.Type, path string) (*h
 			if err != nil {
 				return schema, tyMap, err
 			}
+
 			schema.Blocks = append(schema.Blocks, hclext.BlockSchema{
 				Type:       k,
 				LabelNames: labels,
@@ -93,7 +179,352 @@ func jsonToSchema(in map[string]any, tyMap map[string]cty.Type, path string) (*h
 	return schema, tyMap, nil
 }
 
-// options (object[expand_mode?: any<"none", "expand">]) options to change the retrieve/evaluate behavior
+// jsonToSchemaType parses the type of an attribute in the schema.
+func jsonToSchemaType(in string, key string) (cty.Type, error) {
+	if in == "expr" {
+		// "expr" is a special type that allows you to get the raw expression without evaluating it.
+		return exprCty, nil
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(in), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilType, fmt.Errorf("type expr parse error in %s; %s", key, withoutSubject(diags))
+	}
+	return schemaTypeConstraint(expr, key)
+}
+
+// schemaTypeConstraint returns the type of the type constraint expression, including the special types
+// that wrap a type constraint:
+//
+//   - "expr_and_value" allows you to get both the evaluated value and the raw expression.
+//   - "partial" allows you to get the known parts of partially unknown values.
+//
+// The type of the value can be passed as an argument like "expr_and_value(string)".
+// The argument of "expr_and_value" can be "partial", such as "expr_and_value(partial(map(string)))".
+func schemaTypeConstraint(expr hclsyntax.Expression, key string) (cty.Type, error) {
+	var wrapper string
+	var arg hclsyntax.Expression
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		if len(e.Traversal) == 1 && (e.Traversal.RootName() == "expr_and_value" || e.Traversal.RootName() == "partial") {
+			wrapper = e.Traversal.RootName()
+		}
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "expr_and_value" || e.Name == "partial" {
+			if len(e.Args) != 1 {
+				return cty.NilType, fmt.Errorf("type constraint parse error in %s; %s requires a single type argument", key, e.Name)
+			}
+			wrapper = e.Name
+			arg = e.Args[0]
+		}
+	}
+
+	if wrapper == "" {
+		ty, diags := typeexpr.TypeConstraint(expr)
+		if diags.HasErrors() {
+			return cty.NilType, fmt.Errorf("type constraint parse error in %s; %s", key, withoutSubject(diags))
+		}
+		return ty, nil
+	}
+
+	ty := cty.DynamicPseudoType
+	if arg != nil {
+		var err error
+		ty, err = schemaTypeConstraint(arg, key)
+		if err != nil {
+			return cty.NilType, err
+		}
+	}
+	if _, ok := exprAndValueType(ty); ok {
+		return cty.NilType, fmt.Errorf("type constraint parse error in %s; expr_and_value cannot be passed to %s", key, wrapper)
+	}
+
+	switch wrapper {
+	case "expr_and_value":
+		return exprAndValueCty(ty), nil
+	default:
+		if _, ok := partialType(ty); ok {
+			return cty.NilType, fmt.Errorf("type constraint parse error in %s; partial cannot be passed to partial", key)
+		}
+		return partialCty(ty), nil
+	}
+}
+
+// withDynamicBlockSchema returns the schema with dynamic blocks that generate the nested blocks.
+// This is used to retrieve templates of nested blocks when dynamic blocks are not expanded.
+// If "dynamic" is declared explicitly, the schema is respected.
+// Paths of nested blocks and dynamic blocks are marked in the type map.
+func withDynamicBlockSchema(schema *hclext.BodySchema, tyMap map[string]cty.Type, path string) *hclext.BodySchema {
+	ret := &hclext.BodySchema{
+		Mode:       schema.Mode,
+		Attributes: schema.Attributes,
+		Blocks:     make([]hclext.BlockSchema, len(schema.Blocks)),
+	}
+	declared := false
+	for i, block := range schema.Blocks {
+		key := fmt.Sprintf("%s.%s", path, block.Type)
+		tyMap[key] = nestedBlockCty
+
+		ret.Blocks[i] = hclext.BlockSchema{
+			Type:       block.Type,
+			LabelNames: block.LabelNames,
+			Body:       withDynamicBlockSchema(block.Body, tyMap, key),
+		}
+		if block.Type == "dynamic" {
+			declared = true
+		}
+	}
+	if declared || len(ret.Blocks) == 0 {
+		return ret
+	}
+
+	content := &hclext.BodySchema{}
+	for _, block := range ret.Blocks {
+		content = mergeSchema(content, block.Body)
+	}
+	ret.Blocks = append(ret.Blocks, hclext.BlockSchema{
+		Type:       "dynamic",
+		LabelNames: []string{"type"},
+		Body: &hclext.BodySchema{
+			Attributes: []hclext.AttributeSchema{{Name: "for_each"}, {Name: "iterator"}},
+			Blocks:     []hclext.BlockSchema{{Type: "content", Body: content}},
+		},
+	})
+	tyMap[fmt.Sprintf("%s.dynamic", path)] = dynamicBlockCty
+
+	return ret
+}
+
+// mergeSchema returns a union of the given schemas.
+// Dynamic blocks can generate any nested block, so content must be retrieved with all schemas.
+func mergeSchema(a *hclext.BodySchema, b *hclext.BodySchema) *hclext.BodySchema {
+	ret := &hclext.BodySchema{
+		Attributes: append([]hclext.AttributeSchema{}, a.Attributes...),
+		Blocks:     append([]hclext.BlockSchema{}, a.Blocks...),
+	}
+
+	for _, attr := range b.Attributes {
+		if !slices.ContainsFunc(ret.Attributes, func(s hclext.AttributeSchema) bool { return s.Name == attr.Name }) {
+			ret.Attributes = append(ret.Attributes, attr)
+		}
+	}
+	for _, block := range b.Blocks {
+		i := slices.IndexFunc(ret.Blocks, func(s hclext.BlockSchema) bool { return s.Type == block.Type })
+		if i < 0 {
+			ret.Blocks = append(ret.Blocks, block)
+			continue
+		}
+		ret.Blocks[i] = hclext.BlockSchema{
+			Type:       block.Type,
+			LabelNames: ret.Blocks[i].LabelNames,
+			Body:       mergeSchema(ret.Blocks[i].Body, block.Body),
+		}
+	}
+
+	return ret
+}
+
+// hasSchemaWildcard returns true if the schema contains "*" or "**" at any level.
+func hasSchemaWildcard(in map[string]any) bool {
+	for k, v := range in {
+		if k == "*" || k == "**" {
+			return true
+		}
+		if block, ok := v.(map[string]any); ok && hasSchemaWildcard(block) {
+			return true
+		}
+	}
+	return false
+}
+
+// syntaxSchema is a schema discovered from the syntax of bodies.
+// Attributes and blocks of all bodies at the same path are merged.
+type syntaxSchema struct {
+	attributes map[string]bool
+	blocks     map[string]*syntaxSchema
+	labels     int
+	// labelConflict is true if blocks of the same type have different numbers of labels.
+	labelConflict bool
+}
+
+func newSyntaxSchema(labels int) *syntaxSchema {
+	return &syntaxSchema{attributes: map[string]bool{}, blocks: map[string]*syntaxSchema{}, labels: labels}
+}
+
+// discoverSyntaxSchema discovers the schema from bodies in the native syntax and JSON syntax.
+// JSON bodies have no distinction between attributes and blocks, so names are treated as blocks
+// only if they are used as blocks in the native syntax. Otherwise, they are treated as attributes.
+func discoverSyntaxSchema(bodies []hcl.Body) *syntaxSchema {
+	ret := newSyntaxSchema(0)
+	jsonBodies := []hcl.Body{}
+	for _, body := range bodies {
+		if native, ok := body.(*hclsyntax.Body); ok {
+			ret.collect(native)
+		} else {
+			jsonBodies = append(jsonBodies, body)
+		}
+	}
+	for _, body := range jsonBodies {
+		ret.collectJSON(body)
+	}
+	return ret
+}
+
+func (s *syntaxSchema) collect(body *hclsyntax.Body) {
+	for name := range body.Attributes {
+		s.attributes[name] = true
+	}
+
+	for _, block := range body.Blocks {
+		// Contents of dynamic blocks are merged into the blocks they generate.
+		if block.Type == "dynamic" && len(block.Labels) == 1 {
+			child := s.child(block.Labels[0], -1)
+			for _, content := range block.Body.Blocks {
+				if content.Type == "content" {
+					child.collect(content.Body)
+				}
+			}
+			continue
+		}
+		s.child(block.Type, len(block.Labels)).collect(block.Body)
+	}
+}
+
+func (s *syntaxSchema) collectJSON(body hcl.Body) {
+	// The JSON syntax returns all properties as attributes.
+	attrs, _ := body.JustAttributes()
+
+	headers := []hcl.BlockHeaderSchema{}
+	for name := range attrs {
+		switch child, exists := s.blocks[name]; {
+		case name == "dynamic":
+			headers = append(headers, hcl.BlockHeaderSchema{Type: "dynamic", LabelNames: []string{"type"}})
+		case exists && !child.labelConflict:
+			headers = append(headers, hcl.BlockHeaderSchema{Type: name, LabelNames: make([]string, max(child.labels, 0))})
+		case !exists:
+			s.attributes[name] = true
+		}
+	}
+	if len(headers) == 0 {
+		return
+	}
+
+	content, _, _ := body.PartialContent(&hcl.BodySchema{Blocks: headers})
+	for _, block := range content.Blocks {
+		if block.Type == "dynamic" {
+			child := s.child(block.Labels[0], -1)
+			inner, _, _ := block.Body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "content"}}})
+			for _, content := range inner.Blocks {
+				child.collectJSON(content.Body)
+			}
+			continue
+		}
+		s.blocks[block.Type].collectJSON(block.Body)
+	}
+}
+
+// child returns the schema of the nested block. A negative number of labels means unknown,
+// such as blocks generated by dynamic blocks.
+func (s *syntaxSchema) child(blockType string, labels int) *syntaxSchema {
+	child, exists := s.blocks[blockType]
+	if !exists {
+		child = newSyntaxSchema(labels)
+		s.blocks[blockType] = child
+		return child
+	}
+	switch {
+	case labels < 0:
+	case child.labels < 0:
+		child.labels = labels
+	case child.labels != labels:
+		child.labelConflict = true
+	}
+	return child
+}
+
+// expandSchemaWildcard replaces wildcards in the schema with attributes and blocks discovered from the syntax.
+// "*" is expanded to all attributes, and "**" is expanded to all attributes and nested blocks recursively.
+// Names declared explicitly are respected. Names used as block types are not expanded as attributes.
+func expandSchemaWildcard(in map[string]any, syntax *syntaxSchema, path string) (map[string]any, error) {
+	out := map[string]any{}
+	var wildcard any
+	var recursive bool
+
+	for k, v := range in {
+		switch k {
+		case "**":
+			wildcard = v
+			recursive = true
+		case "*":
+			if !recursive {
+				wildcard = v
+			}
+		default:
+			block, ok := v.(map[string]any)
+			if !ok {
+				out[k] = v
+				continue
+			}
+			child, exists := syntax.blocks[k]
+			if !exists {
+				child = newSyntaxSchema(0)
+			}
+			inner, err := expandSchemaWildcard(block, child, fmt.Sprintf("%s.%s", path, k))
+			if err != nil {
+				return out, err
+			}
+			out[k] = inner
+		}
+	}
+	if wildcard == nil {
+		return out, nil
+	}
+
+	ty, ok := wildcard.(string)
+	if !ok {
+		return out, fmt.Errorf("%s.* is not string, got %T", path, wildcard)
+	}
+
+	for name := range syntax.attributes {
+		if _, exists := in[name]; exists {
+			continue
+		}
+		if _, exists := syntax.blocks[name]; exists {
+			continue
+		}
+		out[name] = ty
+	}
+	if !recursive {
+		return out, nil
+	}
+
+	for blockType, child := range syntax.blocks {
+		if _, exists := in[blockType]; exists {
+			continue
+		}
+		if child.labelConflict {
+			return out, fmt.Errorf("%s.%s has blocks with different numbers of labels; declare the block explicitly instead of using the wildcard", path, blockType)
+		}
+
+		block := map[string]any{"**": ty}
+		if child.labels > 0 {
+			labels := make([]any, child.labels)
+			for i := range labels {
+				labels[i] = fmt.Sprintf("label%d", i)
+			}
+			block["__labels"] = labels
+		}
+		inner, err := expandSchemaWildcard(block, child, fmt.Sprintf("%s.%s", path, blockType))
+		if err != nil {
+			return out, err
+		}
+		out[blockType] = inner
+	}
+
+	return out, nil
+}
+
+// options (object[expand_mode?: any<"none", "expand">, module_ctx?: any<"self", "root">]) options to change the retrieve/evaluate behavior
 var optionsTy = types.NewObject(
 	nil,
 	// Use dynamic properties as optional static properties are not supported.
@@ -116,6 +547,17 @@ func jsonToOption(in map[string]string) (*option, error) {
 				return out, fmt.Errorf("unknown expand mode: %s", v)
 			}
 
+		case "module_ctx":
+			out.ModuleCtxSet = true
+			switch v {
+			case "self":
+				out.ModuleCtx = tflint.SelfModuleCtxType
+			case "root":
+				out.ModuleCtx = tflint.RootModuleCtxType
+			default:
+				return out, fmt.Errorf("unknown module context: %s", v)
+			}
+
 		default:
 			return out, fmt.Errorf("unknown option: %s", k)
 		}
@@ -124,6 +566,83 @@ func jsonToOption(in map[string]string) (*option, error) {
 	return out, nil
 }
 
+// inputs (object[variables?: object[string: any], unknown_variables?: array[string], module_path?: string, original_working_dir?: string, root_module?: object[string: string]]) inputs passed with the "__inputs__" key of mock sources
+func jsonToInputs(in map[string]any) (tester.Inputs, error) {
+	out := tester.Inputs{}
+
+	for k, v := range in {
+		switch k {
+		case "variables":
+			vars, err := jsonToObject(v, "__inputs__.variables")
+			if err != nil {
+				return out, err
+			}
+			out.Variables = map[string]cty.Value{}
+			for name, raw := range vars {
+				src, err := json.Marshal(raw)
+				if err != nil {
+					return out, err
+				}
+				ty, err := ctyjson.ImpliedType(src)
+				if err != nil {
+					return out, fmt.Errorf("__inputs__.variables.%s: %w", name, err)
+				}
+				val, err := ctyjson.Unmarshal(src, ty)
+				if err != nil {
+					return out, fmt.Errorf("__inputs__.variables.%s: %w", name, err)
+				}
+				out.Variables[name] = val
+			}
+
+		case "unknown_variables":
+			names, ok := v.([]any)
+			if !ok {
+				return out, fmt.Errorf("__inputs__.unknown_variables is not array, got %T", v)
+			}
+			for i, name := range names {
+				str, err := jsonToString(name, fmt.Sprintf("__inputs__.unknown_variables[%d]", i))
+				if err != nil {
+					return out, err
+				}
+				out.UnknownVariables = append(out.UnknownVariables, str)
+			}
+
+		case "module_path":
+			str, err := jsonToString(v, "__inputs__.module_path")
+			if err != nil {
+				return out, err
+			}
+			out.ModulePath = str
+
+		case "original_working_dir":
+			str, err := jsonToString(v, "__inputs__.original_working_dir")
+			if err != nil {
+				return out, err
+			}
+			out.OriginalWorkingDir = str
+
+		case "root_module":
+			files, err := jsonToObject(v, "__inputs__.root_module")
+			if err != nil {
+				return out, err
+			}
+			out.RootModule = map[string]string{}
+			for name, src := range files {
+				str, err := jsonToString(src, fmt.Sprintf("__inputs__.root_module.%s", name))
+				if err != nil {
+					return out, err
+				}
+				out.RootModule[name] = str
+			}
+
+		default:
+			return out, fmt.Errorf("unknown input: %s", k)
+		}
+	}
+
+	return out, nil
+}
+
 // typed_block (object<type: string, name: string, config: body, decl_range: range>) representation of a block labeled with type and name
 var typedBlockTy = types.NewObject(
 	[]*types.StaticProperty{
@@ -136,6 +655,7 @@ var typedBlockTy = types.NewObject(
 )
 
 func typedBlocksToJSON(blocks hclext.Blocks, tyMap map[string]cty.Type, path string, runner tflint.Runner) ([]map[string]any, error) {
+	runner = withFileCache(runner)
 	ret := make([]map[string]any, len(blocks))
 
 	for i, block := range blocks {
@@ -165,6 +685,7 @@ var namedBlockTy = types.NewObject(
 )
 
 func namedBlocksToJSON(blocks hclext.Blocks, tyMap map[string]cty.Type, path string, runner tflint.Runner) ([]map[string]any, error) {
+	runner = withFileCache(runner)
 	ret := make([]map[string]any, len(blocks))
 
 	for i, block := range blocks {
@@ -192,6 +713,7 @@ var blockTy = types.NewObject(
 )
 
 func blocksToJSON(blocks hclext.Blocks, tyMap map[string]cty.Type, path string, runner tflint.Runner) ([]map[string]any, error) {
+	runner = withFileCache(runner)
 	ret := make([]map[string]any, len(blocks))
 
 	for i, block := range blocks {
@@ -249,7 +771,14 @@ func bodyToJSON(body *hclext.BodyContent, tyMap map[string]cty.Type, path string
 	ret := map[string]any{}
 
 	for k, attr := range body.Attributes {
-		value, err := exprToJSON(attr.Expr, tyMap, fmt.Sprintf("%s.%s", path, k), runner)
+		key := fmt.Sprintf("%s.%s", path, k)
+		// Attributes retrieved in just-attributes mode have the type of the body.
+		if _, exists := tyMap[key]; !exists {
+			if ty, exists := tyMap[fmt.Sprintf("%s.*", path)]; exists {
+				tyMap[key] = ty
+			}
+		}
+		value, err := exprToJSON(attr.Expr, tyMap, key, runner)
 		if err != nil {
 			return ret, err
 		}
@@ -258,18 +787,31 @@ func bodyToJSON(body *hclext.BodyContent, tyMap map[string]cty.Type, path string
 	}
 
 	for _, block := range body.Blocks {
-		json, err := nestedBlockToJSON(block, tyMap, fmt.Sprintf("%s.%s", path, block.Type), runner)
+		blockType := block.Type
+		var json map[string]any
+		var err error
+
+		if tyMap[fmt.Sprintf("%s.%s", path, block.Type)] == dynamicBlockCty {
+			// Dynamic block templates are returned as the nested block they generate.
+			blockType = block.Labels[0]
+			if tyMap[fmt.Sprintf("%s.%s", path, blockType)] != nestedBlockCty {
+				continue
+			}
+			json, err = dynamicBlockToJSON(block, tyMap, fmt.Sprintf("%s.%s", path, blockType), runner)
+		} else {
+			json, err = nestedBlockToJSON(block, tyMap, fmt.Sprintf("%s.%s", path, block.Type), runner)
+		}
 		if err != nil {
 			return ret, err
 		}
 
-		switch r := ret[block.Type].(type) {
+		switch r := ret[blockType].(type) {
 		case nil:
-			ret[block.Type] = []map[string]any{json}
+			ret[blockType] = []map[string]any{json}
 		case []map[string]any:
-			ret[block.Type] = append(r, json)
+			ret[blockType] = append(r, json)
 		default:
-			panic(fmt.Sprintf("unknown type: %T", ret[block.Type]))
+			panic(fmt.Sprintf("unknown type: %T", ret[blockType]))
 		}
 	}
 
@@ -280,9 +822,13 @@ func bodyToJSON(body *hclext.BodyContent, tyMap map[string]cty.Type, path string
 var exprTy = types.NewObject(
 	[]*types.StaticProperty{
 		types.NewStaticProperty("value", types.A),
+		types.NewStaticProperty("value_source", types.S),
 		types.NewStaticProperty("unknown", types.B),
 		types.NewStaticProperty("sensitive", types.B),
 		types.NewStaticProperty("ephemeral", types.B),
+		types.NewStaticProperty("unknown_paths", types.NewArray(nil, types.NewArray(nil, types.A))),
+		types.NewStaticProperty("sensitive_paths", types.NewArray(nil, types.NewArray(nil, types.A))),
+		types.NewStaticProperty("ephemeral_paths", types.NewArray(nil, types.NewArray(nil, types.A))),
 		types.NewStaticProperty("range", rangeTy),
 	},
 	nil,
@@ -303,6 +849,30 @@ func exprToJSON(expr hcl.Expression, tyMap map[string]cty.Type, path string, run
 		}
 		return rawExprToJSON(expr, file.Bytes), nil
 	}
+	// For the "expr_and_value" type, the raw expression syntax is added as the "value_source".
+	if valueTy, ok := exprAndValueType(ty); ok {
+		ret, err := evaluatedExprToJSON(expr, valueTy, runner)
+		if err != nil {
+			return ret, err
+		}
+		file, err := runner.GetFile(expr.Range().Filename)
+		if err != nil {
+			return ret, fmt.Errorf("type error in %s; %w", expr.Range(), err)
+		}
+		ret["value_source"] = rawExprToJSON(expr, file.Bytes)["value"]
+		return ret, nil
+	}
+
+	return evaluatedExprToJSON(expr, ty, runner)
+}
+
+func evaluatedExprToJSON(expr hcl.Expression, ty cty.Type, runner tflint.Runner) (map[string]any, error) {
+	// For the "partial" type, the known parts of partially unknown values are returned.
+	partial := false
+	if valueTy, ok := partialType(ty); ok {
+		ty = valueTy
+		partial = true
+	}
 
 	ret := map[string]any{
 		"unknown":   false,
@@ -322,7 +892,7 @@ func exprToJSON(expr hcl.Expression, tyMap map[string]cty.Type, path string, run
 		}
 		return ret, err
 	}
-	if value.ContainsMarked() {
+	if value.IsMarked() {
 		ret["unknown"] = true
 		if marks.Contains(value, marks.Sensitive) {
 			ret["sensitive"] = true
@@ -332,8 +902,29 @@ func exprToJSON(expr hcl.Expression, tyMap map[string]cty.Type, path string, run
 		}
 		return ret, nil
 	}
-	if !value.IsWhollyKnown() {
+	if !value.IsKnown() {
+		ret["unknown"] = true
+		return ret, nil
+	}
+	if value.ContainsMarked() || !value.IsWhollyKnown() {
 		ret["unknown"] = true
+		ret["sensitive"] = marks.Contains(value, marks.Sensitive)
+		ret["ephemeral"] = marks.Contains(value, marks.Ephemeral)
+		// "value" is undefined to halt evaluation unless the "partial" type is requested.
+		if !partial {
+			return ret, nil
+		}
+
+		// The known parts are returned and the others are replaced with null.
+		out, err := partialValueToJSON(value, ty, expr.Range())
+		if err != nil {
+			// If the known parts cannot be returned (e.g. the type does not match the schema),
+			// "value" is undefined as if the value is wholly unknown.
+			return ret, nil
+		}
+		for k, v := range out {
+			ret[k] = v
+		}
 		return ret, nil
 	}
 
@@ -347,55 +938,159 @@ func exprToJSON(expr hcl.Expression, tyMap map[string]cty.Type, path string, run
 		return ret, fmt.Errorf("type error in %s; %w", expr.Range(), err)
 	}
 
-	// Convert cty.Value to JSON representation and unmarshal as any type.
-	// This allows values of any type to be valid JSON values.
-	out, err := ctyjson.Marshal(value, ty)
+	val, err := valueToJSON(value, ty, expr.Range())
 	if err != nil {
-		return ret, fmt.Errorf("internal marshal error in %s; %w", expr.Range(), err)
-	}
-	var val any
-	if err := json.Unmarshal(out, &val); err != nil {
-		return ret, fmt.Errorf("internal unmarshal error in %s; %w", expr.Range(), err)
+		return ret, err
 	}
 	ret["value"] = val
 
 	return ret, nil
 }
 
-// raw_expr (object<value: string, range: range>) representation of a raw expression. This is a subset of the expr type.
-var rawExprTy = types.NewObject(
-	[]*types.StaticProperty{
-		types.NewStaticProperty("value", types.S),
-		types.NewStaticProperty("range", rangeTy),
-	},
-	nil,
-)
+// partialValueToJSON returns the JSON representation of the partially unknown value
+// with the paths to the unknown, sensitive, and ephemeral elements.
+func partialValueToJSON(value cty.Value, ty cty.Type, rng hcl.Range) (map[string]any, error) {
+	paths := &partialValuePaths{unknown: [][]any{}, sensitive: [][]any{}, ephemeral: [][]any{}}
+	value, err := partialValue(value, []any{}, paths)
+	if err != nil {
+		return nil, err
+	}
 
-func rawExprToJSON(expr hcl.Expression, src []byte) map[string]any {
-	// "unknown", "sensitive", and "ephemeral" are undefined
-	// because the "value" has not been evaluated.
-	return map[string]any{
-		"value": string(expr.Range().SliceBytes(src)),
-		"range": rangeToJSON(expr.Range()),
+	if ty.HasDynamicTypes() {
+		ty = value.Type()
+	}
+	value, err = convert.Convert(value, ty)
+	if err != nil {
+		return nil, fmt.Errorf("type error in %s; %w", rng, err)
+	}
+	val, err := valueToJSON(value, ty, rng)
+	if err != nil {
+		return nil, err
 	}
+
+	return map[string]any{
+		"value":           val,
+		"unknown_paths":   paths.unknown,
+		"sensitive_paths": paths.sensitive,
+		"ephemeral_paths": paths.ephemeral,
+	}, nil
 }
 
-// nested_block (object<config: object[string: any<expr, array[nested_block]>], labels: array[string], decl_range: range>) representation of a nested block
-var nestedBlockTy = types.NewObject(
-	[]*types.StaticProperty{
-		types.NewStaticProperty("config", types.NewObject(
-			nil,
-			// Same as bodyTy
-			types.NewDynamicProperty(
-				types.S,
-				// Same as types.Or(exprTy, types.NewArray(nil, nestedBlockTy)
-				types.Or(exprTy, types.NewArray(nil, types.NewObject(
-					[]*types.StaticProperty{
-						types.NewStaticProperty("config", types.NewObject(
-							nil,
-							types.NewDynamicProperty(
-								types.S,
-								// block deeper than 3 levels should be any, as recursive type is not supported
+type partialValuePaths struct {
+	unknown   [][]any
+	sensitive [][]any
+	ephemeral [][]any
+}
+
+// partialValue returns a value that replaces unknown and marked elements with null.
+// The paths to all replaced elements are recorded as unknown, and the paths to marked elements
+// are also recorded as sensitive or ephemeral. Sets are replaced as a whole
+// because elements of sets cannot be addressed.
+func partialValue(value cty.Value, path []any, paths *partialValuePaths) (cty.Value, error) {
+	if value.IsMarked() || (value.Type().IsSetType() && value.ContainsMarked()) {
+		// Marked elements are also unknown, as sensitive values are reported as unknown at the top level.
+		paths.unknown = append(paths.unknown, slices.Clone(path))
+		if marks.Contains(value, marks.Sensitive) {
+			paths.sensitive = append(paths.sensitive, slices.Clone(path))
+		}
+		if marks.Contains(value, marks.Ephemeral) {
+			paths.ephemeral = append(paths.ephemeral, slices.Clone(path))
+		}
+		return cty.NullVal(value.Type()), nil
+	}
+	if !value.IsWhollyKnown() && (!value.IsKnown() || value.Type().IsSetType()) {
+		paths.unknown = append(paths.unknown, slices.Clone(path))
+		return cty.NullVal(value.Type()), nil
+	}
+	if value.IsNull() || (value.IsWhollyKnown() && !value.ContainsMarked()) {
+		return value, nil
+	}
+
+	ty := value.Type()
+	switch {
+	case ty.IsListType() || ty.IsTupleType():
+		elems := []cty.Value{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			v, err := partialValue(elem, append(path, len(elems)), paths)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems = append(elems, v)
+		}
+		if ty.IsTupleType() {
+			return cty.TupleVal(elems), nil
+		}
+		return cty.ListVal(elems), nil
+
+	case ty.IsMapType() || ty.IsObjectType():
+		elems := map[string]cty.Value{}
+		for it := value.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			v, err := partialValue(elem, append(path, key.AsString()), paths)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[key.AsString()] = v
+		}
+		if ty.IsObjectType() {
+			return cty.ObjectVal(elems), nil
+		}
+		return cty.MapVal(elems), nil
+
+	default:
+		return cty.NilVal, fmt.Errorf("unexpected partially known value: %s", ty.FriendlyName())
+	}
+}
+
+// valueToJSON converts cty.Value to JSON representation and unmarshals as any type.
+// This allows values of any type to be valid JSON values.
+func valueToJSON(value cty.Value, ty cty.Type, rng hcl.Range) (any, error) {
+	out, err := ctyjson.Marshal(value, ty)
+	if err != nil {
+		return nil, fmt.Errorf("internal marshal error in %s; %w", rng, err)
+	}
+	var val any
+	if err := json.Unmarshal(out, &val); err != nil {
+		return nil, fmt.Errorf("internal unmarshal error in %s; %w", rng, err)
+	}
+	return val, nil
+}
+
+// raw_expr (object<value: string, range: range>) representation of a raw expression. This is a subset of the expr type.
+var rawExprTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("value", types.S),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+func rawExprToJSON(expr hcl.Expression, src []byte) map[string]any {
+	// "unknown", "sensitive", and "ephemeral" are undefined
+	// because the "value" has not been evaluated.
+	return map[string]any{
+		"value": string(expr.Range().SliceBytes(src)),
+		"range": rangeToJSON(expr.Range()),
+	}
+}
+
+// nested_block (object<config: object[string: any<expr, array[nested_block]>], labels: array[string], decl_range: range>) representation of a nested block
+var nestedBlockTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("config", types.NewObject(
+			nil,
+			// Same as bodyTy
+			types.NewDynamicProperty(
+				types.S,
+				// Same as types.Or(exprTy, types.NewArray(nil, nestedBlockTy)
+				types.Or(exprTy, types.NewArray(nil, types.NewObject(
+					[]*types.StaticProperty{
+						types.NewStaticProperty("config", types.NewObject(
+							nil,
+							types.NewDynamicProperty(
+								types.S,
+								// block deeper than 3 levels should be any, as recursive type is not supported
 								types.Or(exprTy, types.NewArray(nil, types.A)),
 							)),
 						),
@@ -418,13 +1113,1068 @@ func nestedBlockToJSON(block *hclext.Block, tyMap map[string]cty.Type, path stri
 		return nil, err
 	}
 
-	return map[string]any{
+	ret := map[string]any{
 		"config":     body,
 		"labels":     block.Labels,
 		"decl_range": rangeToJSON(block.DefRange),
+	}
+
+	// Blocks generated by dynamic blocks have the declaration range of the dynamic block.
+	// The whole range of the dynamic block and its iterator name are added to tell where the block came from.
+	if block.Type != "dynamic" {
+		dynamic, err := findDynamicBlock(block.DefRange, runner)
+		if err != nil {
+			return nil, err
+		}
+		if dynamic != nil {
+			ret["dynamic_range"] = rangeToJSON(dynamic.Range())
+			ret["dynamic_iterator"] = dynamicBlockIterator(dynamic)
+		}
+	}
+
+	return ret, nil
+}
+
+// findDynamicBlock returns the dynamic block declared at the range in the native syntax.
+// If not found, it returns nil.
+func findDynamicBlock(rng hcl.Range, runner tflint.Runner) (*hclsyntax.Block, error) {
+	if r, ok := runner.(*fileCacheRunner); ok {
+		blocks, err := r.dynamicBlocksIn(rng.Filename)
+		if err != nil {
+			return nil, err
+		}
+		return blocks[rng.Start.Byte], nil
+	}
+
+	file, err := runner.GetFile(rng.Filename)
+	if err != nil {
+		return nil, err
+	}
+	return dynamicBlocks(file)[rng.Start.Byte], nil
+}
+
+// dynamicBlocks returns dynamic blocks in the file by their start bytes.
+// Files other than the native syntax (e.g. JSON) have no dynamic blocks.
+func dynamicBlocks(file *hcl.File) map[int]*hclsyntax.Block {
+	ret := map[int]*hclsyntax.Block{}
+	if file == nil {
+		return ret
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return ret
+	}
+
+	hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+		if block, ok := node.(*hclsyntax.Block); ok && block.Type == "dynamic" {
+			ret[block.DefRange().Start.Byte] = block
+		}
+		return nil
+	})
+	return ret
+}
+
+// dynamicBlockIterator returns the iterator name of the dynamic block.
+// If the "iterator" is not set, it is the same as the block type.
+func dynamicBlockIterator(block *hclsyntax.Block) string {
+	if attr, exists := block.Body.Attributes["iterator"]; exists {
+		if iterator := hcl.ExprAsKeyword(attr.Expr); iterator != "" {
+			return iterator
+		}
+	}
+	if len(block.Labels) == 0 {
+		return ""
+	}
+	return block.Labels[0]
+}
+
+// fileCacheRunner caches files retrieved during a conversion.
+// Converting nested blocks looks up the file of each block, so the cache
+// avoids retrieving the same file from the host for every block.
+type fileCacheRunner struct {
+	tflint.Runner
+
+	files         map[string]*hcl.File
+	dynamicBlocks map[string]map[int]*hclsyntax.Block
+}
+
+func withFileCache(runner tflint.Runner) tflint.Runner {
+	if _, ok := runner.(*fileCacheRunner); ok {
+		return runner
+	}
+	return &fileCacheRunner{
+		Runner:        runner,
+		files:         map[string]*hcl.File{},
+		dynamicBlocks: map[string]map[int]*hclsyntax.Block{},
+	}
+}
+
+func (r *fileCacheRunner) GetFile(filename string) (*hcl.File, error) {
+	if file, exists := r.files[filename]; exists {
+		return file, nil
+	}
+	file, err := r.Runner.GetFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.files[filename] = file
+	return file, nil
+}
+
+func (r *fileCacheRunner) dynamicBlocksIn(filename string) (map[int]*hclsyntax.Block, error) {
+	if blocks, exists := r.dynamicBlocks[filename]; exists {
+		return blocks, nil
+	}
+	file, err := r.GetFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.dynamicBlocks[filename] = dynamicBlocks(file)
+	return r.dynamicBlocks[filename], nil
+}
+
+// dynamic_block (object<config: object[string: any<expr, array[nested_block]>], labels: array[string], decl_range: range, dynamic: boolean, for_each: raw_expr, iterator: string>) representation of a dynamic block template
+func dynamicBlockToJSON(block *hclext.Block, tyMap map[string]cty.Type, path string, runner tflint.Runner) (map[string]any, error) {
+	// Labels of generated blocks are not supported, so labels are always nil.
+	var labels []string
+	ret := map[string]any{
+		"config":     map[string]any{},
+		"labels":     labels,
+		"decl_range": rangeToJSON(block.DefRange),
+		"dynamic":    true,
+		"iterator":   block.Labels[0],
+	}
+
+	if attr, exists := block.Body.Attributes["for_each"]; exists {
+		file, err := runner.GetFile(attr.Expr.Range().Filename)
+		if err != nil {
+			return nil, err
+		}
+		ret["for_each"] = rawExprToJSON(attr.Expr, file.Bytes)
+	}
+	if attr, exists := block.Body.Attributes["iterator"]; exists {
+		if iterator := hcl.ExprAsKeyword(attr.Expr); iterator != "" {
+			ret["iterator"] = iterator
+		}
+	}
+
+	for _, content := range block.Body.Blocks {
+		if content.Type != "content" {
+			continue
+		}
+		config, err := bodyToJSON(filterContent(content.Body, tyMap, path), tyMap, path, runner)
+		if err != nil {
+			return nil, err
+		}
+		ret["config"] = config
+	}
+
+	return ret, nil
+}
+
+// filterContent returns the body content without attributes and blocks not declared in the path.
+// The content of dynamic blocks is retrieved with the union of all nested block schemas.
+func filterContent(body *hclext.BodyContent, tyMap map[string]cty.Type, path string) *hclext.BodyContent {
+	ret := &hclext.BodyContent{Attributes: hclext.Attributes{}}
+
+	for name, attr := range body.Attributes {
+		if _, exists := tyMap[fmt.Sprintf("%s.%s", path, name)]; exists {
+			ret.Attributes[name] = attr
+		}
+	}
+	for _, block := range body.Blocks {
+		if _, exists := tyMap[fmt.Sprintf("%s.%s", path, block.Type)]; exists {
+			ret.Blocks = append(ret.Blocks, block)
+		}
+	}
+
+	return ret
+}
+
+// file (object<name: string, kind: string, override: boolean, size: number, lines: number, range: range>) representation of a config file
+var fileTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("name", types.S),
+		types.NewStaticProperty("kind", types.S),
+		types.NewStaticProperty("override", types.B),
+		types.NewStaticProperty("size", types.N),
+		types.NewStaticProperty("lines", types.N),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+func filesToJSON(files map[string]*hcl.File) []map[string]any {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]map[string]any, len(names))
+	for i, name := range names {
+		src := files[name].Bytes
+
+		kind := "hcl"
+		if strings.HasSuffix(name, ".json") {
+			kind = "json"
+		}
+		// Override files are named "override.tf" or end with "_override.tf".
+		// See https://developer.hashicorp.com/terraform/language/files/override
+		base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(name), ".json"), ".tf")
+		override := base == "override" || strings.HasSuffix(base, "_override")
+
+		lines := bytes.Count(src, []byte("\n"))
+		if len(src) > 0 && !bytes.HasSuffix(src, []byte("\n")) {
+			lines++
+		}
+
+		ret[i] = map[string]any{
+			"name":     name,
+			"kind":     kind,
+			"override": override,
+			"size":     len(src),
+			"lines":    lines,
+			"range":    rangeToJSON(hcl.Range{Filename: name, Start: hcl.InitialPos, End: hcl.InitialPos}),
+		}
+	}
+	return ret
+}
+
+// comment (object<text: string, style: string, range: range, precedes: comment_target, trails: comment_target>) representation of a comment
+var commentTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("text", types.S),
+		types.NewStaticProperty("style", types.S),
+		types.NewStaticProperty("range", rangeTy),
+		types.NewStaticProperty("precedes", commentTargetTy),
+		types.NewStaticProperty("trails", commentTargetTy),
+	},
+	nil,
+)
+
+// comment_target (object<kind: string, name: string, labels: array[string], range: range>) representation of a block or attribute with comments
+var commentTargetTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("kind", types.S),
+		types.NewStaticProperty("name", types.S),
+		types.NewStaticProperty("labels", types.NewArray(nil, types.S)),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+// commentTarget is a block or attribute to which comments can be attached.
+type commentTarget struct {
+	kind   string
+	name   string
+	labels []string
+	rng    hcl.Range
+	src    hcl.Range
+}
+
+func commentsToJSON(file *hcl.File) []map[string]any {
+	ret := []map[string]any{}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		// Comments in JSON syntax are not supported.
+		return ret
+	}
+	targets := collectCommentTargets(body)
+
+	// Lexing is done after parsing, so there should be no errors.
+	tokens, _ := hclsyntax.LexConfig(file.Bytes, body.SrcRange.Filename, hcl.InitialPos)
+
+	for i, token := range tokens {
+		if token.Type != hclsyntax.TokenComment {
+			continue
+		}
+
+		text, rng := commentTextRange(token)
+		var style string
+		switch {
+		case strings.HasPrefix(text, "#"):
+			style = "#"
+		case strings.HasPrefix(text, "//"):
+			style = "//"
+		default:
+			style = "/* */"
+		}
+
+		comment := map[string]any{
+			"text":  text,
+			"style": style,
+			"range": rangeToJSON(rng),
+		}
+
+		// A comment is leading if it is the first token on the line.
+		leading := i == 0 || tokens[i-1].Type == hclsyntax.TokenNewline || endsLine(tokens[i-1])
+		if leading {
+			if target := precedingTarget(tokens[i+1:], rng.End.Line, targets); target != nil {
+				comment["precedes"] = target.toJSON()
+			}
+		} else {
+			if target := trailingTarget(rng.Start, targets); target != nil {
+				comment["trails"] = target.toJSON()
+			}
+		}
+
+		ret = append(ret, comment)
+	}
+
+	return ret
+}
+
+func (t *commentTarget) toJSON() map[string]any {
+	labels := t.labels
+	if labels == nil {
+		labels = []string{}
+	}
+	return map[string]any{
+		"kind":   t.kind,
+		"name":   t.name,
+		"labels": labels,
+		"range":  rangeToJSON(t.rng),
+	}
+}
+
+// collectCommentTargets returns all blocks and attributes in the body in source order.
+// Outer targets come before inner targets that start at the same position.
+func collectCommentTargets(body *hclsyntax.Body) []*commentTarget {
+	targets := []*commentTarget{}
+
+	for _, attr := range body.Attributes {
+		targets = append(targets, &commentTarget{
+			kind: "attribute",
+			name: attr.Name,
+			rng:  attr.SrcRange,
+			src:  attr.SrcRange,
+		})
+	}
+	for _, block := range body.Blocks {
+		targets = append(targets, &commentTarget{
+			kind:   "block",
+			name:   block.Type,
+			labels: block.Labels,
+			rng:    block.DefRange(),
+			src:    block.Range(),
+		})
+		targets = append(targets, collectCommentTargets(block.Body)...)
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].src.Start.Byte < targets[j].src.Start.Byte
+	})
+	return targets
+}
+
+// precedingTarget returns the target that starts right after the comment.
+// Contiguous comments are skipped, but blank lines are not allowed in between.
+func precedingTarget(tokens hclsyntax.Tokens, endLine int, targets []*commentTarget) *commentTarget {
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline:
+			continue
+		case hclsyntax.TokenComment:
+			_, rng := commentTextRange(token)
+			if rng.Start.Line > endLine+1 {
+				return nil
+			}
+			endLine = rng.End.Line
+			continue
+		}
+
+		if token.Range.Start.Line > endLine+1 {
+			return nil
+		}
+		for _, target := range targets {
+			if target.src.Start.Byte == token.Range.Start.Byte {
+				return target
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// trailingTarget returns the target that ends right before the comment on the same line.
+func trailingTarget(start hcl.Pos, targets []*commentTarget) *commentTarget {
+	var ret *commentTarget
+	for _, target := range targets {
+		end := target.src.End
+		if end.Line != start.Line || end.Byte > start.Byte {
+			continue
+		}
+		if ret == nil || end.Byte > ret.src.End.Byte {
+			ret = target
+		}
+	}
+	return ret
+}
+
+// commentTextRange returns the text and range of the comment token
+// without the trailing newline of single-line comments.
+func commentTextRange(token hclsyntax.Token) (string, hcl.Range) {
+	if !endsLine(token) {
+		return string(token.Bytes), token.Range
+	}
+
+	text := strings.TrimRight(string(token.Bytes), "\r\n")
+	rng := token.Range
+	rng.End = hcl.Pos{
+		Line:   rng.Start.Line,
+		Column: rng.Start.Column + utf8.RuneCountInString(text),
+		Byte:   rng.Start.Byte + len(text),
+	}
+	return text, rng
+}
+
+// endsLine returns whether the token includes a trailing newline.
+// Single-line comments ("#" and "//") are such tokens.
+func endsLine(token hclsyntax.Token) bool {
+	return token.Type == hclsyntax.TokenComment && bytes.HasSuffix(token.Bytes, []byte("\n"))
+}
+
+// unformatted_file (object<filename: string, diffs: array[format_diff]>) representation of a file that is not in the canonical format
+var unformattedFileTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("filename", types.S),
+		types.NewStaticProperty("diffs", types.NewArray(nil, formatDiffTy)),
+	},
+	nil,
+)
+
+// format_diff (object<range: range, formatted: string>) representation of a range that differs from the canonical format
+var formatDiffTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("range", rangeTy),
+		types.NewStaticProperty("formatted", types.S),
+	},
+	nil,
+)
+
+func unformattedFileToJSON(name string, file *hcl.File) map[string]any {
+	src := file.Bytes
+	formatted := hclwrite.Format(src)
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+
+	srcLines := bytes.Split(src, []byte("\n"))
+	formattedLines := bytes.Split(formatted, []byte("\n"))
+
+	// The formatter only adjusts spacing, so lines usually correspond one-to-one.
+	// If not, the whole file is treated as a diff.
+	if len(srcLines) != len(formattedLines) {
+		return map[string]any{
+			"filename": name,
+			"diffs": []map[string]any{
+				{
+					"range":     rangeToJSON(linesRange(name, srcLines, 0, len(srcLines))),
+					"formatted": string(formatted),
+				},
+			},
+		}
+	}
+
+	diffs := []map[string]any{}
+	for i := 0; i < len(srcLines); i++ {
+		if bytes.Equal(srcLines[i], formattedLines[i]) {
+			continue
+		}
+
+		// Consecutive changed lines are grouped into one diff.
+		j := i + 1
+		for j < len(srcLines) && !bytes.Equal(srcLines[j], formattedLines[j]) {
+			j++
+		}
+
+		diffs = append(diffs, map[string]any{
+			"range":     rangeToJSON(linesRange(name, srcLines, i, j)),
+			"formatted": string(bytes.Join(formattedLines[i:j], []byte("\n"))),
+		})
+		i = j
+	}
+
+	return map[string]any{
+		"filename": name,
+		"diffs":    diffs,
+	}
+}
+
+// linesRange returns the range from the start of lines[start] to the end of lines[end-1],
+// excluding the trailing newline.
+func linesRange(filename string, lines [][]byte, start int, end int) hcl.Range {
+	startByte := 0
+	for _, line := range lines[:start] {
+		startByte += len(line) + 1
+	}
+	endByte := startByte
+	for _, line := range lines[start : end-1] {
+		endByte += len(line) + 1
+	}
+	last := lines[end-1]
+	endByte += len(last)
+
+	return hcl.Range{
+		Filename: filename,
+		Start:    hcl.Pos{Line: start + 1, Column: 1, Byte: startByte},
+		End:      hcl.Pos{Line: end, Column: utf8.RuneCount(last) + 1, Byte: endByte},
+	}
+}
+
+// lock_provider (object<source: string, version: lock_value, constraints: lock_value, hashes: array[lock_value], decl_range: range>) representation of a provider in the dependency lock file
+var lockProviderTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("source", types.S),
+		types.NewStaticProperty("version", lockValueTy),
+		types.NewStaticProperty("constraints", lockValueTy),
+		types.NewStaticProperty("hashes", types.NewArray(nil, lockValueTy)),
+		types.NewStaticProperty("decl_range", rangeTy),
+	},
+	nil,
+)
+
+// lock_value (object<value: string, range: range>) representation of a string value in the dependency lock file
+var lockValueTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("value", types.S),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+var lockfileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "provider", LabelNames: []string{"source"}},
+	},
+}
+
+var lockProviderSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "version", Required: true},
+		{Name: "constraints"},
+		{Name: "hashes"},
+	},
+}
+
+func lockfileToJSON(src []byte, filename string) ([]map[string]any, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	content, diags := file.Body.Content(lockfileSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	ret := make([]map[string]any, len(content.Blocks))
+	for i, block := range content.Blocks {
+		body, diags := block.Body.Content(lockProviderSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		provider := map[string]any{
+			"source":     block.Labels[0],
+			"hashes":     []map[string]any{},
+			"decl_range": rangeToJSON(block.DefRange),
+		}
+
+		version, err := lockValueToJSON(body.Attributes["version"].Expr)
+		if err != nil {
+			return nil, err
+		}
+		provider["version"] = version
+
+		if attr, exists := body.Attributes["constraints"]; exists {
+			constraints, err := lockValueToJSON(attr.Expr)
+			if err != nil {
+				return nil, err
+			}
+			provider["constraints"] = constraints
+		}
+
+		if attr, exists := body.Attributes["hashes"]; exists {
+			exprs, diags := hcl.ExprList(attr.Expr)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			hashes := make([]map[string]any, len(exprs))
+			for j, expr := range exprs {
+				hash, err := lockValueToJSON(expr)
+				if err != nil {
+					return nil, err
+				}
+				hashes[j] = hash
+			}
+			provider["hashes"] = hashes
+		}
+
+		ret[i] = provider
+	}
+
+	return ret, nil
+}
+
+func lockValueToJSON(expr hcl.Expression) (map[string]any, error) {
+	var value string
+	if diags := gohcl.DecodeExpression(expr, nil, &value); diags.HasErrors() {
+		return nil, diags
+	}
+
+	return map[string]any{
+		"value": value,
+		"range": rangeToJSON(expr.Range()),
 	}, nil
 }
 
+// tfvar (object<name: string, filename: string, expr: raw_expr, value: any, decl_range: range>) representation of a variable assignment in a variable definitions file
+var tfvarTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("name", types.S),
+		types.NewStaticProperty("filename", types.S),
+		types.NewStaticProperty("expr", rawExprTy),
+		types.NewStaticProperty("value", types.A),
+		types.NewStaticProperty("decl_range", rangeTy),
+	},
+	nil,
+)
+
+func tfvarsToJSON(file *hcl.File) ([]map[string]any, error) {
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Range.Start.Byte < sorted[j].Range.Start.Byte
+	})
+
+	ret := make([]map[string]any, len(sorted))
+	for i, attr := range sorted {
+		// Variable definitions files are evaluated without any variables or functions.
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		val, err := valueToJSON(value, value.Type(), attr.Expr.Range())
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = map[string]any{
+			"name":       attr.Name,
+			"filename":   attr.Range.Filename,
+			"expr":       rawExprToJSON(attr.Expr, file.Bytes),
+			"value":      val,
+			"decl_range": rangeToJSON(attr.Range),
+		}
+	}
+
+	return ret, nil
+}
+
+// test_file (object<filename: string, config: body, range: range>) representation of a Terraform test file
+var testFileTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("filename", types.S),
+		types.NewStaticProperty("config", bodyTy),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+// testFileLabels is label names of blocks in Terraform test files.
+// Labels are set automatically unless the schema sets "__labels" explicitly.
+var testFileLabels = map[string][]any{
+	"run":           {"name"},
+	"provider":      {"name"},
+	"mock_provider": {"name"},
+	"mock_resource": {"type"},
+	"mock_data":     {"type"},
+}
+
+func withTestFileLabels(in map[string]any) map[string]any {
+	for k, v := range in {
+		block, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, exists := block["__labels"]; !exists {
+			if labels, exists := testFileLabels[k]; exists {
+				block["__labels"] = labels
+			}
+		}
+		withTestFileLabels(block)
+	}
+	return in
+}
+
+func testFilesToJSON(names []string, contents []*hclext.BodyContent, tyMap map[string]cty.Type, runner tflint.Runner) ([]map[string]any, error) {
+	runner = withFileCache(runner)
+	ret := make([]map[string]any, len(names))
+
+	for i, name := range names {
+		config, err := bodyToJSON(contents[i], tyMap, "schema", runner)
+		if err != nil {
+			return ret, err
+		}
+
+		ret[i] = map[string]any{
+			"filename": name,
+			"config":   config,
+			"range":    rangeToJSON(hcl.Range{Filename: name, Start: hcl.InitialPos, End: hcl.InitialPos}),
+		}
+	}
+
+	return ret, nil
+}
+
+// installed_module (object<key: string, name: string, installed: boolean, source: string, version: string, dir: string, decl_range: range>) representation of a module call and its installation
+var installedModuleTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("key", types.S),
+		types.NewStaticProperty("name", types.S),
+		types.NewStaticProperty("installed", types.B),
+		types.NewStaticProperty("source", types.S),
+		types.NewStaticProperty("version", types.S),
+		types.NewStaticProperty("dir", types.S),
+		types.NewStaticProperty("decl_range", rangeTy),
+	},
+	nil,
+)
+
+// moduleManifest is the structure of .terraform/modules/modules.json.
+type moduleManifest struct {
+	Modules []moduleManifestRecord `json:"Modules"`
+}
+
+type moduleManifestRecord struct {
+	Key     string `json:"Key"`
+	Source  string `json:"Source"`
+	Version string `json:"Version"`
+	Dir     string `json:"Dir"`
+}
+
+func installedModulesToJSON(calls hclext.Blocks, path addrs.Module, manifest moduleManifest) []map[string]any {
+	records := map[string]moduleManifestRecord{}
+	for _, record := range manifest.Modules {
+		records[record.Key] = record
+	}
+
+	ret := []map[string]any{}
+	for _, call := range calls {
+		// Keys are dot-separated module call names from the root module.
+		// e.g. "vpc.subnets"
+		key := strings.Join(append(append([]string{}, path...), call.Labels[0]), ".")
+		module := map[string]any{
+			"key":        key,
+			"name":       call.Labels[0],
+			"installed":  false,
+			"decl_range": rangeToJSON(call.DefRange),
+		}
+		record, exists := records[key]
+		if !exists {
+			// Module calls missing from the manifest are not installed yet.
+			// The source, version, and dir are not set.
+			ret = append(ret, module)
+			continue
+		}
+
+		module["installed"] = true
+		module["source"] = record.Source
+		module["dir"] = record.Dir
+		// Version is recorded only for registry modules.
+		if record.Version != "" {
+			module["version"] = record.Version
+		}
+		ret = append(ret, module)
+	}
+
+	return ret
+}
+
+// type_constraint (object<type: string, element: type_constraint, attributes: object[string: type_constraint], elements: array[type_constraint], optional: boolean, default: any>) representation of a type constraint
+var typeConstraintTy = types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))
+
+func typeConstraintToJSON(ty cty.Type, defaults *typeexpr.Defaults, rng hcl.Range) (map[string]any, error) {
+	switch {
+	case ty == cty.DynamicPseudoType:
+		return map[string]any{"type": "any"}, nil
+
+	case ty.IsPrimitiveType():
+		return map[string]any{"type": ty.FriendlyName()}, nil
+
+	case ty.IsListType(), ty.IsSetType(), ty.IsMapType():
+		var name string
+		switch {
+		case ty.IsListType():
+			name = "list"
+		case ty.IsSetType():
+			name = "set"
+		default:
+			name = "map"
+		}
+
+		// Collections have a single element type, which is stored at key "".
+		element, err := typeConstraintToJSON(ty.ElementType(), childDefaults(defaults, ""), rng)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": name, "element": element}, nil
+
+	case ty.IsObjectType():
+		attributes := map[string]any{}
+		for name, attrTy := range ty.AttributeTypes() {
+			attr, err := typeConstraintToJSON(attrTy, childDefaults(defaults, name), rng)
+			if err != nil {
+				return nil, err
+			}
+
+			attr["optional"] = ty.AttributeOptional(name)
+			if defaults != nil {
+				if value, exists := defaults.DefaultValues[name]; exists {
+					val, err := valueToJSON(value, value.Type(), rng)
+					if err != nil {
+						return nil, err
+					}
+					attr["default"] = val
+				}
+			}
+			attributes[name] = attr
+		}
+		return map[string]any{"type": "object", "attributes": attributes}, nil
+
+	case ty.IsTupleType():
+		elements := make([]map[string]any, len(ty.TupleElementTypes()))
+		for i, elemTy := range ty.TupleElementTypes() {
+			element, err := typeConstraintToJSON(elemTy, childDefaults(defaults, strconv.Itoa(i)), rng)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return map[string]any{"type": "tuple", "elements": elements}, nil
+
+	default:
+		// should never happen
+		return nil, fmt.Errorf("unsupported type in %s: %s", rng, ty.FriendlyName())
+	}
+}
+
+func childDefaults(defaults *typeexpr.Defaults, key string) *typeexpr.Defaults {
+	if defaults == nil {
+		return nil
+	}
+	return defaults.Children[key]
+}
+
+// resource_provider (object<name: string, alias: string, source: string, config: body, decl_range: range, unknown: boolean>) representation of a provider configuration used by a resource
+var resourceProviderTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("name", types.S),
+		types.NewStaticProperty("alias", types.S),
+		types.NewStaticProperty("source", types.S),
+		types.NewStaticProperty("config", bodyTy),
+		types.NewStaticProperty("decl_range", rangeTy),
+		types.NewStaticProperty("unknown", types.B),
+	},
+	nil,
+)
+
+func resourceProviderToJSON(name string, alias string, source string, block *hclext.Block, tyMap map[string]cty.Type, runner tflint.Runner) (map[string]any, error) {
+	ret := map[string]any{"name": name, "source": source}
+	if alias != "" {
+		ret["alias"] = alias
+	}
+	// If the provider is not configured explicitly, the config is undefined.
+	if block == nil {
+		return ret, nil
+	}
+
+	config, err := bodyToJSON(filterContent(block.Body, tyMap, "schema"), tyMap, "schema", withFileCache(runner))
+	if err != nil {
+		return nil, err
+	}
+	ret["config"] = config
+	ret["decl_range"] = rangeToJSON(block.DefRange)
+
+	return ret, nil
+}
+
+// dependency_graph (object<nodes: array[dependency_node], edges: array[dependency_edge]>) representation of dependencies between blocks
+var dependencyGraphTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("nodes", types.NewArray(nil, dependencyNodeTy)),
+		types.NewStaticProperty("edges", types.NewArray(nil, dependencyEdgeTy)),
+	},
+	nil,
+)
+
+// dependency_node (object<address: string, kind: string, decl_range: range>) representation of a block in the dependency graph
+var dependencyNodeTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("address", types.S),
+		types.NewStaticProperty("kind", types.S),
+		types.NewStaticProperty("decl_range", rangeTy),
+	},
+	nil,
+)
+
+// dependency_edge (object<from: string, to: string, depends_on: boolean, range: range>) representation of a reference between blocks
+var dependencyEdgeTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("from", types.S),
+		types.NewStaticProperty("to", types.S),
+		types.NewStaticProperty("depends_on", types.B),
+		types.NewStaticProperty("range", rangeTy),
+	},
+	nil,
+)
+
+type dependencyNode struct {
+	address  string
+	kind     string
+	declRng  hcl.Range
+	body     *hclsyntax.Body
+	metaArgs bool
+}
+
+func dependencyGraphToJSON(files map[string]*hcl.File) map[string]any {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := []*dependencyNode{}
+	for _, name := range names {
+		// Files other than the native syntax (e.g. JSON) are not supported as they have no schema.
+		body, ok := files[name].Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				nodes = append(nodes, &dependencyNode{address: strings.Join(block.Labels, "."), kind: block.Type, declRng: block.DefRange(), body: block.Body, metaArgs: true})
+			case (block.Type == "data" || block.Type == "ephemeral") && len(block.Labels) == 2:
+				nodes = append(nodes, &dependencyNode{address: fmt.Sprintf("%s.%s", block.Type, strings.Join(block.Labels, ".")), kind: block.Type, declRng: block.DefRange(), body: block.Body, metaArgs: true})
+			case block.Type == "module" && len(block.Labels) == 1:
+				nodes = append(nodes, &dependencyNode{address: "module." + block.Labels[0], kind: block.Type, declRng: block.DefRange(), body: block.Body, metaArgs: true})
+			case block.Type == "variable" && len(block.Labels) == 1:
+				nodes = append(nodes, &dependencyNode{address: "var." + block.Labels[0], kind: block.Type, declRng: block.DefRange(), body: block.Body})
+			case block.Type == "output" && len(block.Labels) == 1:
+				nodes = append(nodes, &dependencyNode{address: "output." + block.Labels[0], kind: block.Type, declRng: block.DefRange(), body: block.Body, metaArgs: true})
+			case block.Type == "locals":
+				// Each local value is a node.
+				attrs := make([]*hclsyntax.Attribute, 0, len(block.Body.Attributes))
+				for _, attr := range block.Body.Attributes {
+					attrs = append(attrs, attr)
+				}
+				sort.Slice(attrs, func(i, j int) bool { return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte })
+				for _, attr := range attrs {
+					nodes = append(nodes, &dependencyNode{address: "local." + attr.Name, kind: "local", declRng: attr.NameRange, body: &hclsyntax.Body{Attributes: hclsyntax.Attributes{attr.Name: attr}}})
+				}
+			}
+		}
+	}
+
+	addrs := map[string]bool{}
+	for _, node := range nodes {
+		addrs[node.address] = true
+	}
+
+	nodesJSON := make([]map[string]any, len(nodes))
+	edgesJSON := []map[string]any{}
+	for i, node := range nodes {
+		nodesJSON[i] = map[string]any{
+			"address":    node.address,
+			"kind":       node.kind,
+			"decl_range": rangeToJSON(node.declRng),
+		}
+
+		for _, ref := range collectDependencyRefs(node.body, node.metaArgs) {
+			to := dependencyAddr(ref.traversal)
+			if to == "" || to == node.address || !addrs[to] {
+				continue
+			}
+			edgesJSON = append(edgesJSON, map[string]any{
+				"from":       node.address,
+				"to":         to,
+				"depends_on": ref.dependsOn,
+				"range":      rangeToJSON(ref.traversal.SourceRange()),
+			})
+		}
+	}
+
+	return map[string]any{"nodes": nodesJSON, "edges": edgesJSON}
+}
+
+type dependencyRef struct {
+	traversal hcl.Traversal
+	dependsOn bool
+}
+
+// collectDependencyRefs returns references in the body in source order.
+// If metaArgs is true, references in "depends_on" are marked as explicit dependencies.
+func collectDependencyRefs(body *hclsyntax.Body, metaArgs bool) []dependencyRef {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte })
+
+	refs := []dependencyRef{}
+	for _, attr := range attrs {
+		for _, traversal := range attr.Expr.Variables() {
+			refs = append(refs, dependencyRef{traversal: traversal, dependsOn: metaArgs && attr.Name == "depends_on"})
+		}
+	}
+	for _, block := range body.Blocks {
+		refs = append(refs, collectDependencyRefs(block.Body, false)...)
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].traversal.SourceRange().Start.Byte < refs[j].traversal.SourceRange().Start.Byte
+	})
+	return refs
+}
+
+// dependencyAddr returns the address of the block referenced by the traversal.
+// If the traversal does not refer to a block, it returns an empty string.
+func dependencyAddr(traversal hcl.Traversal) string {
+	names := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		attr, ok := step.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+		names = append(names, attr.Name)
+	}
+
+	switch names[0] {
+	case "count", "each", "self", "path", "terraform":
+		return ""
+	case "data", "ephemeral":
+		if len(names) < 3 {
+			return ""
+		}
+		return strings.Join(names[:3], ".")
+	default:
+		if len(names) < 2 {
+			return ""
+		}
+		return strings.Join(names[:2], ".")
+	}
+}
+
 // range (object<filename: string, start: pos, end: pos>) range of a source file
 var rangeTy = types.NewObject(
 	[]*types.StaticProperty{
@@ -7,6 +7,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
 	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
 	"github.com/terraform-linters/tflint-ruleset-opa/opa/tester"
@@ -72,6 +73,56 @@ func TestJSONToSchema(t *testing.T) {
 			},
 			tyMap: map[string]cty.Type{"schema.instance_type": exprCty},
 		},
+		{
+			name:  "expr_and_value type",
+			input: map[string]any{"instance_type": "expr_and_value"},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "instance_type"}},
+			},
+			tyMap: map[string]cty.Type{"schema.instance_type": exprAndValueCty(cty.DynamicPseudoType)},
+		},
+		{
+			name:  "expr_and_value type with value type",
+			input: map[string]any{"tags": "expr_and_value(map(string))"},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "tags"}},
+			},
+			tyMap: map[string]cty.Type{"schema.tags": exprAndValueCty(cty.Map(cty.String))},
+		},
+		{
+			name:  "partial type",
+			input: map[string]any{"tags": "partial(map(string))"},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "tags"}},
+			},
+			tyMap: map[string]cty.Type{"schema.tags": partialCty(cty.Map(cty.String))},
+		},
+		{
+			name:  "partial type without value type",
+			input: map[string]any{"tags": "partial"},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "tags"}},
+			},
+			tyMap: map[string]cty.Type{"schema.tags": partialCty(cty.DynamicPseudoType)},
+		},
+		{
+			name:  "expr_and_value type with partial type",
+			input: map[string]any{"tags": "expr_and_value(partial(map(string)))"},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "tags"}},
+			},
+			tyMap: map[string]cty.Type{"schema.tags": exprAndValueCty(partialCty(cty.Map(cty.String)))},
+		},
+		{
+			name:  "partial type with expr_and_value type",
+			input: map[string]any{"tags": "partial(expr_and_value)"},
+			err:   "type constraint parse error in schema.tags; expr_and_value cannot be passed to partial",
+		},
+		{
+			name:  "invalid expr_and_value arguments",
+			input: map[string]any{"tags": "expr_and_value(string, number)"},
+			err:   "type constraint parse error in schema.tags; expr_and_value requires a single type argument",
+		},
 		{
 			name:  "invalid schema type",
 			input: map[string]any{"nested": map[string]any{"number": 1}},
@@ -87,6 +138,29 @@ func TestJSONToSchema(t *testing.T) {
 			input: map[string]any{"dynamic": map[string]any{"__labels": "type"}},
 			err:   "schema.dynamic.__labels is not array of string, got string",
 		},
+		{
+			name:  "just attributes",
+			input: map[string]any{"tags": map[string]any{"__just_attributes": "string"}},
+			want: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{
+						Type: "tags",
+						Body: &hclext.BodySchema{Mode: hclext.SchemaJustAttributesMode},
+					},
+				},
+			},
+			tyMap: map[string]cty.Type{"schema.tags.*": cty.String},
+		},
+		{
+			name:  "just attributes with other keys",
+			input: map[string]any{"__just_attributes": "string", "name": "string"},
+			err:   "schema.__just_attributes cannot be used with other keys",
+		},
+		{
+			name:  "invalid just attributes type",
+			input: map[string]any{"__just_attributes": "unknown"},
+			err:   `type constraint parse error in schema.__just_attributes; Invalid type specification; The keyword "unknown" is not a valid type specification.`,
+		},
 	}
 
 	for _, test := range tests {
@@ -118,6 +192,283 @@ func TestJSONToSchema(t *testing.T) {
 	}
 }
 
+func TestWithDynamicBlockSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *hclext.BodySchema
+		want  *hclext.BodySchema
+		tyMap map[string]cty.Type
+	}{
+		{
+			name: "attribute schema",
+			input: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "instance_type"}},
+			},
+			want: &hclext.BodySchema{
+				Attributes: []hclext.AttributeSchema{{Name: "instance_type"}},
+				Blocks:     []hclext.BlockSchema{},
+			},
+			tyMap: map[string]cty.Type{},
+		},
+		{
+			name: "block schema",
+			input: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{
+						Type: "ebs_block_device",
+						Body: &hclext.BodySchema{
+							Attributes: []hclext.AttributeSchema{{Name: "volume_size"}},
+						},
+					},
+				},
+			},
+			want: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{
+						Type: "ebs_block_device",
+						Body: &hclext.BodySchema{
+							Attributes: []hclext.AttributeSchema{{Name: "volume_size"}},
+							Blocks:     []hclext.BlockSchema{},
+						},
+					},
+					{
+						Type:       "dynamic",
+						LabelNames: []string{"type"},
+						Body: &hclext.BodySchema{
+							Attributes: []hclext.AttributeSchema{{Name: "for_each"}, {Name: "iterator"}},
+							Blocks: []hclext.BlockSchema{
+								{
+									Type: "content",
+									Body: &hclext.BodySchema{
+										Attributes: []hclext.AttributeSchema{{Name: "volume_size"}},
+										Blocks:     []hclext.BlockSchema{},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			tyMap: map[string]cty.Type{
+				"schema.ebs_block_device": nestedBlockCty,
+				"schema.dynamic":          dynamicBlockCty,
+			},
+		},
+		{
+			name: "explicit dynamic block schema",
+			input: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{
+						Type:       "dynamic",
+						LabelNames: []string{"type"},
+						Body:       &hclext.BodySchema{},
+					},
+				},
+			},
+			want: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{
+						Type:       "dynamic",
+						LabelNames: []string{"type"},
+						Body:       &hclext.BodySchema{Blocks: []hclext.BlockSchema{}},
+					},
+				},
+			},
+			tyMap: map[string]cty.Type{"schema.dynamic": nestedBlockCty},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tyMap := map[string]cty.Type{}
+			got := withDynamicBlockSchema(test.input, tyMap, "schema")
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+
+			opts := cmp.Options{
+				cmp.Comparer(func(x, y cty.Type) bool {
+					return x.GoString() == y.GoString()
+				}),
+			}
+			if diff := cmp.Diff(test.tyMap, tyMap, opts); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExpandSchemaWildcard(t *testing.T) {
+	config := `
+resource "aws_instance" "main" {
+  ami  = "ami-12345678"
+  tags = { Name = "main" }
+
+  ebs_block_device {
+    volume_size = 10
+  }
+
+  dynamic "ebs_block_device" {
+    for_each = var.devices
+    content {
+      encrypted = true
+    }
+  }
+
+  dynamic "ephemeral_block_device" {
+    for_each = var.ephemeral_devices
+    content {
+      device_name = "/dev/sdb"
+    }
+  }
+
+  provisioner "local-exec" {
+    command = "echo"
+  }
+}`
+	jsonConfig := `
+{
+  "monitoring": true,
+  "ebs_block_device": [{ "iops": 3000 }],
+  "metadata_options": { "http_tokens": "required" },
+  "dynamic": {
+    "ephemeral_block_device": {
+      "for_each": "${var.more_devices}",
+      "content": { "virtual_name": "ephemeral0" }
+    }
+  }
+}`
+	conflictConfig := `
+resource "aws_s3_bucket" "main" {
+  rule {
+    id = "default"
+  }
+  rule "named" {
+    id = "named"
+  }
+}`
+
+	tests := []struct {
+		name     string
+		input    map[string]any
+		conflict bool
+		want     map[string]any
+		err      string
+	}{
+		{
+			name:  "no wildcard",
+			input: map[string]any{"ami": "string"},
+			want:  map[string]any{"ami": "string"},
+		},
+		{
+			name:  "attributes",
+			input: map[string]any{"*": "any", "tags": "map(string)"},
+			want: map[string]any{
+				"ami":              "any",
+				"tags":             "map(string)",
+				"monitoring":       "any",
+				"metadata_options": "any",
+			},
+		},
+		{
+			name:  "attributes recursively",
+			input: map[string]any{"**": "expr"},
+			want: map[string]any{
+				"ami":              "expr",
+				"tags":             "expr",
+				"monitoring":       "expr",
+				"metadata_options": "expr",
+				"ebs_block_device": map[string]any{
+					"volume_size": "expr",
+					"encrypted":   "expr",
+					"iops":        "expr",
+				},
+				"ephemeral_block_device": map[string]any{
+					"device_name":  "expr",
+					"virtual_name": "expr",
+				},
+				"provisioner": map[string]any{
+					"__labels": []any{"label0"},
+					"command":  "expr",
+				},
+			},
+		},
+		{
+			name:  "nested block",
+			input: map[string]any{"ebs_block_device": map[string]any{"*": "number"}},
+			want: map[string]any{
+				"ebs_block_device": map[string]any{
+					"volume_size": "number",
+					"encrypted":   "number",
+					"iops":        "number",
+				},
+			},
+		},
+		{
+			name:  "invalid type",
+			input: map[string]any{"*": map[string]any{}},
+			err:   "schema.* is not string, got map[string]interface {}",
+		},
+		{
+			name:     "different numbers of labels",
+			input:    map[string]any{"**": "expr"},
+			conflict: true,
+			err:      "schema.rule has blocks with different numbers of labels; declare the block explicitly instead of using the wildcard",
+		},
+		{
+			name:     "different numbers of labels declared explicitly",
+			input:    map[string]any{"rule": map[string]any{"*": "string"}},
+			conflict: true,
+			want: map[string]any{
+				"rule": map[string]any{"id": "string"},
+			},
+		},
+	}
+
+	parse := func(src string) []hcl.Body {
+		file, diags := hclsyntax.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		bodies := []hcl.Body{}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			bodies = append(bodies, block.Body)
+		}
+		return bodies
+	}
+	jsonFile, diags := hcljson.Parse([]byte(jsonConfig), "main.tf.json")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	bodies := append(parse(config), jsonFile.Body)
+	conflictBodies := parse(conflictConfig)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			syntax := discoverSyntaxSchema(bodies)
+			if test.conflict {
+				syntax = discoverSyntaxSchema(conflictBodies)
+			}
+
+			got, err := expandSchemaWildcard(test.input, syntax, "schema")
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
 func TestJSONToOption(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -139,6 +490,16 @@ func TestJSONToOption(t *testing.T) {
 			input: map[string]string{"expand_mode": "expand"},
 			want:  &option{ExpandMode: tflint.ExpandModeExpand, ExpandModeSet: true},
 		},
+		{
+			name:  "module_ctx = self",
+			input: map[string]string{"module_ctx": "self"},
+			want:  &option{ModuleCtx: tflint.SelfModuleCtxType, ModuleCtxSet: true},
+		},
+		{
+			name:  "module_ctx = root",
+			input: map[string]string{"module_ctx": "root"},
+			want:  &option{ModuleCtx: tflint.RootModuleCtxType, ModuleCtxSet: true},
+		},
 		{
 			name:  "unknown option",
 			input: map[string]string{"unknown": "option"},
@@ -149,6 +510,11 @@ func TestJSONToOption(t *testing.T) {
 			input: map[string]string{"expand_mode": "unknown"},
 			err:   "unknown expand mode: unknown",
 		},
+		{
+			name:  "unknown module_ctx",
+			input: map[string]string{"module_ctx": "unknown"},
+			err:   "unknown module context: unknown",
+		},
 	}
 
 	for _, test := range tests {
@@ -171,6 +537,86 @@ func TestJSONToOption(t *testing.T) {
 	}
 }
 
+func TestJSONToInputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  tester.Inputs
+		err   string
+	}{
+		{
+			name: "empty",
+			want: tester.Inputs{},
+		},
+		{
+			name: "variables",
+			input: map[string]any{"variables": map[string]any{
+				"instance_type": "t2.micro",
+				"ports":         []any{80, 443},
+			}},
+			want: tester.Inputs{Variables: map[string]cty.Value{
+				"instance_type": cty.StringVal("t2.micro"),
+				"ports":         cty.TupleVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+			}},
+		},
+		{
+			name:  "unknown_variables",
+			input: map[string]any{"unknown_variables": []any{"instance_type", "ports"}},
+			want:  tester.Inputs{UnknownVariables: []string{"instance_type", "ports"}},
+		},
+		{
+			name:  "module_path and original_working_dir",
+			input: map[string]any{"module_path": "module.network", "original_working_dir": "/work"},
+			want:  tester.Inputs{ModulePath: "module.network", OriginalWorkingDir: "/work"},
+		},
+		{
+			name:  "root_module",
+			input: map[string]any{"module_path": "module.network", "root_module": map[string]any{"main.tf": `provider "aws" {}`}},
+			want:  tester.Inputs{ModulePath: "module.network", RootModule: map[string]string{"main.tf": `provider "aws" {}`}},
+		},
+		{
+			name:  "unknown input",
+			input: map[string]any{"unknown": "input"},
+			err:   "unknown input: unknown",
+		},
+		{
+			name:  "invalid variables",
+			input: map[string]any{"variables": "foo"},
+			err:   "__inputs__.variables is not object, got string",
+		},
+		{
+			name:  "invalid unknown_variables",
+			input: map[string]any{"unknown_variables": []any{1}},
+			err:   "__inputs__.unknown_variables[0] is not string, got int",
+		},
+		{
+			name:  "invalid root_module",
+			input: map[string]any{"root_module": map[string]any{"main.tf": 1}},
+			err:   "__inputs__.root_module.main.tf is not string, got int",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := jsonToInputs(test.input)
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			opt := cmp.Comparer(func(x, y cty.Value) bool { return x.RawEquals(y) })
+			if diff := cmp.Diff(test.want, got, opt); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
 func TestTypedBlocksToJSON(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -593,6 +1039,98 @@ func TestExprToJSON(t *testing.T) {
 			},
 			source: `variable "foo" {}`,
 		},
+		{
+			name:  "composite unknown list",
+			input: parse("[var.foo]"),
+			ty:    partialCty(cty.List(cty.String)),
+			want: map[string]any{
+				"value":           []any{nil},
+				"unknown":         true,
+				"sensitive":       false,
+				"ephemeral":       false,
+				"unknown_paths":   [][]any{{0}},
+				"sensitive_paths": [][]any{},
+				"ephemeral_paths": [][]any{},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 10, "byte": 9},
+				},
+			},
+			source: `variable "foo" {}`,
+		},
+		{
+			name:  "partially unknown map without partial",
+			input: parse(`{ Name = "main", Owner = var.foo }`),
+			ty:    cty.Map(cty.String),
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 35, "byte": 34},
+				},
+			},
+			source: `variable "foo" {}`,
+		},
+		{
+			name:  "partially sensitive object without partial",
+			input: parse(`{ name = "main", secrets = [var.foo] }`),
+			ty:    cty.DynamicPseudoType,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": true,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 39, "byte": 38},
+				},
+			},
+			source: `variable "foo" { sensitive = true }`,
+		},
+		{
+			name:  "partially unknown map",
+			input: parse(`{ Name = "main", Owner = var.foo }`),
+			ty:    partialCty(cty.Map(cty.String)),
+			want: map[string]any{
+				"value":           map[string]any{"Name": "main", "Owner": nil},
+				"unknown":         true,
+				"sensitive":       false,
+				"ephemeral":       false,
+				"unknown_paths":   [][]any{{"Owner"}},
+				"sensitive_paths": [][]any{},
+				"ephemeral_paths": [][]any{},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 35, "byte": 34},
+				},
+			},
+			source: `variable "foo" {}`,
+		},
+		{
+			name:  "partially sensitive object",
+			input: parse(`{ name = "main", secrets = [var.foo] }`),
+			ty:    partialCty(cty.DynamicPseudoType),
+			want: map[string]any{
+				"value":           map[string]any{"name": "main", "secrets": []any{nil}},
+				"unknown":         true,
+				"sensitive":       true,
+				"ephemeral":       false,
+				"unknown_paths":   [][]any{{"secrets", 0}},
+				"sensitive_paths": [][]any{{"secrets", 0}},
+				"ephemeral_paths": [][]any{},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 39, "byte": 38},
+				},
+			},
+			source: `variable "foo" { sensitive = true }`,
+		},
 		{
 			name:  "sensitive",
 			input: parse("var.foo"),
@@ -625,6 +1163,48 @@ func TestExprToJSON(t *testing.T) {
 			},
 			source: `variable "foo" { sensitive = true }`,
 		},
+		{
+			name:  "nested sensitive element",
+			input: parse(`{ db = { user = "admin", password = var.foo }, owner = var.bar }`),
+			ty:    partialCty(cty.DynamicPseudoType),
+			want: map[string]any{
+				"value":           map[string]any{"db": map[string]any{"user": "admin", "password": nil}, "owner": nil},
+				"unknown":         true,
+				"sensitive":       true,
+				"ephemeral":       false,
+				"unknown_paths":   [][]any{{"db", "password"}, {"owner"}},
+				"sensitive_paths": [][]any{{"db", "password"}},
+				"ephemeral_paths": [][]any{},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 65, "byte": 64},
+				},
+			},
+			source: `
+variable "foo" { sensitive = true }
+variable "bar" {}`,
+		},
+		{
+			name:  "composite sensitive list",
+			input: parse("[var.foo]"),
+			ty:    partialCty(cty.List(cty.String)),
+			want: map[string]any{
+				"value":           []any{nil},
+				"unknown":         true,
+				"sensitive":       true,
+				"ephemeral":       false,
+				"unknown_paths":   [][]any{{0}},
+				"sensitive_paths": [][]any{{0}},
+				"ephemeral_paths": [][]any{},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 10, "byte": 9},
+				},
+			},
+			source: `variable "foo" { sensitive = true }`,
+		},
 		{
 			name:  "ephemeral",
 			input: parse("var.foo"),
@@ -657,6 +1237,26 @@ func TestExprToJSON(t *testing.T) {
 			},
 			source: `variable "foo" { ephemeral = true }`,
 		},
+		{
+			name:  "composite ephemeral list",
+			input: parse("[var.foo]"),
+			ty:    partialCty(cty.List(cty.String)),
+			want: map[string]any{
+				"value":           []any{nil},
+				"unknown":         true,
+				"sensitive":       false,
+				"ephemeral":       true,
+				"unknown_paths":   [][]any{{0}},
+				"sensitive_paths": [][]any{},
+				"ephemeral_paths": [][]any{{0}},
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 10, "byte": 9},
+				},
+			},
+			source: `variable "foo" { ephemeral = true }`,
+		},
 		{
 			name:  "sensitive + ephemeral",
 			input: parse("var.foo"),
@@ -692,6 +1292,49 @@ variable "foo" {
 			source: `
 resource "aws_instance" "main" {
   ignore_changes = [instance_type]
+}`,
+		},
+		{
+			name:  "expr_and_value type",
+			input: parseWithPos(`"logs"`, hcl.Pos{Line: 3, Column: 12, Byte: 46}),
+			ty:    exprAndValueCty(cty.String),
+			want: map[string]any{
+				"value":        "logs",
+				"value_source": `"logs"`,
+				"unknown":      false,
+				"sensitive":    false,
+				"ephemeral":    false,
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 3, "column": 12, "byte": 46},
+					"end":      map[string]int{"line": 3, "column": 18, "byte": 52},
+				},
+			},
+			source: `
+resource "aws_s3_bucket" "main" {
+  bucket = "logs"
+}`,
+		},
+		{
+			name:  "expr_and_value type with unknown value",
+			input: parseWithPos(`"${var.env}-logs"`, hcl.Pos{Line: 5, Column: 12, Byte: 65}),
+			ty:    exprAndValueCty(cty.String),
+			want: map[string]any{
+				"value_source": `"${var.env}-logs"`,
+				"unknown":      true,
+				"sensitive":    false,
+				"ephemeral":    false,
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 5, "column": 12, "byte": 65},
+					"end":      map[string]int{"line": 5, "column": 29, "byte": 82},
+				},
+			},
+			source: `
+variable "env" {}
+
+resource "aws_s3_bucket" "main" {
+  bucket = "${var.env}-logs"
 }`,
 		},
 		{
@@ -751,9 +1394,64 @@ func TestNestedBlockToJSON(t *testing.T) {
 				"decl_range": emptyRange,
 			},
 		},
+		{
+			name: "generated by dynamic block",
+			input: &hclext.Block{
+				Type: "ingress",
+				Body: &hclext.BodyContent{},
+				DefRange: hcl.Range{
+					Filename: "main.tf",
+					Start:    hcl.Pos{Line: 2, Column: 3, Byte: 24},
+					End:      hcl.Pos{Line: 2, Column: 20, Byte: 41},
+				},
+			},
+			want: map[string]any{
+				"config": map[string]any{},
+				"labels": []string(nil),
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 2, "column": 3, "byte": 24},
+					"end":      map[string]int{"line": 2, "column": 20, "byte": 41},
+				},
+				"dynamic_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 2, "column": 3, "byte": 24},
+					"end":      map[string]int{"line": 6, "column": 4, "byte": 107},
+				},
+				"dynamic_iterator": "rule",
+			},
+		},
+		{
+			name: "block type starting with dynamic",
+			input: &hclext.Block{
+				Type: "dynamic_criteria",
+				Body: &hclext.BodyContent{},
+				DefRange: hcl.Range{
+					Filename: "main.tf",
+					Start:    hcl.Pos{Line: 7, Column: 3, Byte: 110},
+					End:      hcl.Pos{Line: 7, Column: 19, Byte: 126},
+				},
+			},
+			want: map[string]any{
+				"config": map[string]any{},
+				"labels": []string(nil),
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 7, "column": 3, "byte": 110},
+					"end":      map[string]int{"line": 7, "column": 19, "byte": 126},
+				},
+			},
+		},
 	}
 
-	runner, diags := tester.NewRunner(map[string]string{})
+	runner, diags := tester.NewRunner(map[string]string{"main.tf": `resource "a" "main" {
+  dynamic "ingress" {
+    for_each = var.rules
+    iterator = rule
+    content {}
+  }
+  dynamic_criteria {}
+}`})
 	if diags.HasErrors() {
 		t.Fatal(diags)
 	}
@@ -772,6 +1470,52 @@ func TestNestedBlockToJSON(t *testing.T) {
 	}
 }
 
+type getFileCountRunner struct {
+	tflint.Runner
+
+	calls map[string]int
+}
+
+func (r *getFileCountRunner) GetFile(filename string) (*hcl.File, error) {
+	r.calls[filename]++
+	return r.Runner.GetFile(filename)
+}
+
+func TestFileCacheRunner(t *testing.T) {
+	runner, diags := tester.NewRunner(map[string]string{"main.tf": `
+resource "aws_security_group" "main" {
+  ingress {}
+  ingress {}
+  egress {}
+}`})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	content, err := runner.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type:       "resource",
+				LabelNames: []string{"type", "name"},
+				Body: &hclext.BodySchema{
+					Blocks: []hclext.BlockSchema{{Type: "ingress", Body: &hclext.BodySchema{}}, {Type: "egress", Body: &hclext.BodySchema{}}},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counter := &getFileCountRunner{Runner: runner, calls: map[string]int{}}
+	if _, err := typedBlocksToJSON(content.Blocks, map[string]cty.Type{}, "schema", counter); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(map[string]int{"main.tf": 1}, counter.calls); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestRangeToJSON(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -40,7 +40,7 @@ func (f *Function) mockDecl() Function {
 	// Mock function takes test inputs as its last argument.
 	// e.g. terraform.mock_resources(resourceType, schema, options, `{"main.tf": "foo = 1"}`)
 	args := f.Decl.Decl.FuncArgs().Args
-	args = append(args, types.NewObject(nil, types.NewDynamicProperty(types.S, types.S)))
+	args = append(args, sourcesTy)
 
 	return Function{
 		Decl: &rego.Function{
@@ -57,6 +57,62 @@ func (f *Function) mockDecl() Function {
 	}
 }
 
+// mockInputsKey is the key of the sources passed to mock functions for inputs other than files,
+// such as variable values. e.g. {"main.tf": "...", "__inputs__": {"variables": {"foo": 1}}}
+const mockInputsKey = "__inputs__"
+
+// sources (object[string: any]) sources of mock functions, which are file contents, with optional inputs.
+// The values are not typed as strings so that inputs can be passed. They are checked at runtime instead.
+var sourcesTy = types.NewObject(nil, types.NewDynamicProperty(types.S, types.A))
+
+type mockRunnerCacheKey string
+
+// mockRunner returns a test runner for the sources passed to mock functions.
+// Inputs such as variable values are taken from the "__inputs__" key of the sources if present.
+// Runners are cached per query, so mock functions called with the same sources share a runner.
+func mockRunner(ctx rego.BuiltinContext, sourcesArg *ast.Term) (tflint.Runner, error) {
+	key := mockRunnerCacheKey(sourcesArg.String())
+	if ctx.Cache != nil {
+		if runner, exists := ctx.Cache.Get(key); exists {
+			return runner.(tflint.Runner), nil
+		}
+	}
+
+	var obj map[string]any
+	if err := ast.As(sourcesArg.Value, &obj); err != nil {
+		return nil, err
+	}
+	sources := map[string]string{}
+	inputs := tftester.Inputs{}
+	for name, v := range obj {
+		if name == mockInputsKey {
+			in, err := jsonToObject(v, mockInputsKey)
+			if err != nil {
+				return nil, err
+			}
+			inputs, err = jsonToInputs(in)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		src, err := jsonToString(v, name)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = src
+	}
+	runner, diags := tftester.NewRunnerWithInputs(sources, inputs)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if ctx.Cache != nil {
+		ctx.Cache.Put(key, runner)
+	}
+	return runner, nil
+}
+
 // Function1 represents a custom OPA function with 1 argument.
 type Function1 struct {
 	Function
@@ -74,19 +130,26 @@ func (f *Function1) Tester() *tester.Builtin {
 	return f.Function.asTester(f.Rego())
 }
 
+// TestOnlyFunction1 creates a function with the same declaration as the passed function,
+// but always returns an error. This is used to compile test-only functions outside of testing.
+func TestOnlyFunction1(f *Function1) *Function1 {
+	return &Function1{
+		Function: f.Function,
+		Impl: func(_ rego.BuiltinContext, _ *ast.Term) (*ast.Term, error) {
+			return nil, fmt.Errorf("%s is only available in tests", f.Decl.Name)
+		},
+	}
+}
+
 // MockFunction1 creates a mock function for Function1.
 func MockFunction1(base func(tflint.Runner) *Function1) *Function2 {
 	return &Function2{
 		Function: base(nil).mockDecl(),
 		Impl: func(ctx rego.BuiltinContext, a *ast.Term, sourcesArg *ast.Term) (*ast.Term, error) {
-			var sources map[string]string
-			if err := ast.As(sourcesArg.Value, &sources); err != nil {
+			runner, err := mockRunner(ctx, sourcesArg)
+			if err != nil {
 				return nil, err
 			}
-			runner, diags := tftester.NewRunner(sources)
-			if diags.HasErrors() {
-				return nil, diags
-			}
 			return base(runner).Impl(ctx, a)
 		},
 	}
@@ -114,14 +177,10 @@ func MockFunction2(base func(tflint.Runner) *Function2) *Function3 {
 	return &Function3{
 		Function: base(nil).mockDecl(),
 		Impl: func(ctx rego.BuiltinContext, a *ast.Term, b *ast.Term, sourcesArg *ast.Term) (*ast.Term, error) {
-			var sources map[string]string
-			if err := ast.As(sourcesArg.Value, &sources); err != nil {
+			runner, err := mockRunner(ctx, sourcesArg)
+			if err != nil {
 				return nil, err
 			}
-			runner, diags := tftester.NewRunner(sources)
-			if diags.HasErrors() {
-				return nil, diags
-			}
 			return base(runner).Impl(ctx, a, b)
 		},
 	}
@@ -149,14 +208,10 @@ func MockFunction3(base func(tflint.Runner) *Function3) *Function4 {
 	return &Function4{
 		Function: base(nil).mockDecl(),
 		Impl: func(ctx rego.BuiltinContext, a *ast.Term, b *ast.Term, c *ast.Term, sourcesArg *ast.Term) (*ast.Term, error) {
-			var sources map[string]string
-			if err := ast.As(sourcesArg.Value, &sources); err != nil {
+			runner, err := mockRunner(ctx, sourcesArg)
+			if err != nil {
 				return nil, err
 			}
-			runner, diags := tftester.NewRunner(sources)
-			if diags.HasErrors() {
-				return nil, diags
-			}
 			return base(runner).Impl(ctx, a, b, c)
 		},
 	}
@@ -195,3 +250,23 @@ func (f *FunctionDyn) Rego() func(*rego.Rego) {
 func (f *FunctionDyn) Tester() *tester.Builtin {
 	return f.Function.asTester(f.Rego())
 }
+
+// MockFunctionDyn creates a mock function for FunctionDyn.
+func MockFunctionDyn(base func(tflint.Runner) *FunctionDyn) *FunctionDyn {
+	decl := base(nil).mockDecl()
+	// Terms may include an output operand, so only declared arguments are used.
+	argc := len(decl.Decl.Decl.FuncArgs().Args)
+
+	return &FunctionDyn{
+		Function: decl,
+		Impl: func(ctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+			args, sourcesArg := terms[:argc-1], terms[argc-1]
+
+			runner, err := mockRunner(ctx, sourcesArg)
+			if err != nil {
+				return nil, err
+			}
+			return base(runner).Impl(ctx, args)
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/types"
@@ -172,6 +173,36 @@ func ExprCallFunc() *Function1 {
 	}
 }
 
+// hcl.format: out := hcl.format(src)
+//
+// Rewrite the given HCL source code to the canonical format.
+// This is equivalent to hclwrite.Format in hashicorp/hcl.
+//
+//	src (string) HCL source code.
+//
+// Returns:
+//
+//	out (string) formatted source code.
+func FormatFunc() *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "hcl.format",
+				Decl:    types.NewFunction(types.Args(types.S), types.S),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, srcArg *ast.Term) (*ast.Term, error) {
+			var src string
+			if err := ast.As(srcArg.Value, &src); err != nil {
+				return nil, err
+			}
+
+			return ast.StringTerm(string(hclwrite.Format([]byte(src)))), nil
+		},
+	}
+}
+
 func astAsExpr(v *ast.Term) (hcl.Expression, string, error) {
 	var exprMap map[string]any
 	if err := ast.As(v.Value, &exprMap); err != nil {
@@ -343,3 +343,36 @@ func TestExprCallFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "unformatted",
+			src:  "resource \"aws_instance\" \"main\" {\ninstance_type=\"t2.micro\"\n  ami = \"ami-123456\"\n}\n",
+			want: "resource \"aws_instance\" \"main\" {\n  instance_type = \"t2.micro\"\n  ami           = \"ami-123456\"\n}\n",
+		},
+		{
+			name: "formatted",
+			src:  "foo = 1\n",
+			want: "foo = 1\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := rego.BuiltinContext{}
+			got, err := FormatFunc().Impl(ctx, ast.StringTerm(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(ast.String(test.want).String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
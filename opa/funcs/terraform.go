@@ -1,29 +1,67 @@
 package funcs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/rego"
 	"github.com/open-policy-agent/opa/v1/types"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
+	"github.com/terraform-linters/tflint-plugin-sdk/terraform/addrs"
+	"github.com/terraform-linters/tflint-plugin-sdk/terraform/lang/marks"
 	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
+	"github.com/terraform-linters/tflint-ruleset-opa/opa/lang"
+	"github.com/terraform-linters/tflint/terraform"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 )
 
 type option struct {
 	ExpandMode    tflint.ExpandMode
 	ExpandModeSet bool
+	ModuleCtx     tflint.ModuleCtxType
+	ModuleCtxSet  bool
 }
 
 func (o *option) AsGetModuleContentOptions() *tflint.GetModuleContentOption {
-	if o.ExpandModeSet {
-		return &tflint.GetModuleContentOption{ExpandMode: o.ExpandMode}
+	if o.ExpandModeSet || o.ModuleCtxSet {
+		return &tflint.GetModuleContentOption{ExpandMode: o.ExpandMode, ModuleCtx: o.ModuleCtx}
 	}
 	return nil
 }
 
+// Schema returns the schema used to retrieve the content with the option.
+// Dynamic blocks are retrieved only when they are not expanded, as templates of nested blocks.
+func (o *option) Schema(schema *hclext.BodySchema, tyMap map[string]cty.Type) *hclext.BodySchema {
+	if o.ExpandModeSet && o.ExpandMode == tflint.ExpandModeNone {
+		return withDynamicBlockSchema(schema, tyMap, "schema")
+	}
+	return schema
+}
+
+// Runner returns a runner that evaluates expressions in the module context of the option.
+func (o *option) Runner(runner tflint.Runner) tflint.Runner {
+	if o.ModuleCtxSet {
+		return &moduleCtxRunner{Runner: runner, moduleCtx: o.ModuleCtx}
+	}
+	return runner
+}
+
 // terraform.resources: resources := terraform.resources(resource_type, schema, options)
 //
 // Returns Terraform resources.
@@ -87,10 +125,6 @@ func DataSourcesFunc(runner tflint.Runner) *Function3 {
 			if err := ast.As(schema.Value, &schemaJSON); err != nil {
 				return nil, err
 			}
-			innerSchema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
-			if err != nil {
-				return nil, err
-			}
 			var optionJSON map[string]string
 			if err := ast.As(options.Value, &optionJSON); err != nil {
 				return nil, err
@@ -99,6 +133,22 @@ func DataSourcesFunc(runner tflint.Runner) *Function3 {
 			if err != nil {
 				return nil, err
 			}
+			dataHeader := hcl.BlockHeaderSchema{Type: "data", LabelNames: []string{"type", "name"}}
+			schemaJSON, err = expandSchema(
+				schemaJSON,
+				runner,
+				option.ModuleCtx,
+				schemaTarget{path: []hcl.BlockHeaderSchema{dataHeader}, name: typeName},
+				schemaTarget{path: []hcl.BlockHeaderSchema{{Type: "check", LabelNames: []string{"name"}}, dataHeader}, name: typeName},
+			)
+			if err != nil {
+				return nil, err
+			}
+			innerSchema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+			if err != nil {
+				return nil, err
+			}
+			innerSchema = option.Schema(innerSchema, tyMap)
 
 			content, err := runner.GetModuleContent(&hclext.BodySchema{
 				Blocks: []hclext.BlockSchema{
@@ -143,7 +193,7 @@ func DataSourcesFunc(runner tflint.Runner) *Function3 {
 				}
 			}
 
-			out, err := typedBlocksToJSON(blocks, tyMap, "schema", runner)
+			out, err := typedBlocksToJSON(blocks, tyMap, "schema", option.Runner(runner))
 			if err != nil {
 				return nil, err
 			}
@@ -348,7 +398,7 @@ func LocalsFunc(runner tflint.Runner) *Function1 {
 
 			locals := []map[string]any{}
 			for _, block := range content.Blocks {
-				out, err := localsToJSON(block.Body.Attributes, runner)
+				out, err := localsToJSON(block.Body.Attributes, option.Runner(runner))
 				if err != nil {
 					return nil, err
 				}
@@ -560,18 +610,11 @@ func ModuleRangeFunc(runner tflint.Runner) *FunctionDyn {
 			},
 		},
 		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
-			files, err := runner.GetFiles()
+			dir, err := moduleDir(runner)
 			if err != nil {
 				return nil, err
 			}
 
-			// If there is no file, the current directory is assumed.
-			var dir string
-			for path := range files {
-				dir = filepath.Dir(path)
-				break
-			}
-
 			rng := hcl.Range{
 				Filename: filepath.Join(dir, "main.tf"),
 				Start:    hcl.InitialPos,
@@ -587,142 +630,2167 @@ func ModuleRangeFunc(runner tflint.Runner) *FunctionDyn {
 	}
 }
 
-func typedBlockFunc(typeArg *ast.Term, schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
-	var typeName string
-	if err := ast.As(typeArg.Value, &typeName); err != nil {
-		return nil, err
-	}
-	var schemaJSON map[string]any
-	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
-		return nil, err
-	}
-	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
-	if err != nil {
-		return nil, err
-	}
-	var optionJSON map[string]string
-	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
-		return nil, err
-	}
-	option, err := jsonToOption(optionJSON)
-	if err != nil {
-		return nil, err
+// terraform.files: files := terraform.files()
+//
+// Returns Terraform config files in the current module.
+//
+// Returns:
+//
+//	files (array[file]) Terraform config files
+func FilesFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.files",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, fileTy)),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			files, err := runner.GetFiles()
+			if err != nil {
+				return nil, err
+			}
+
+			out := filesToJSON(files)
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
 	}
+}
 
-	content, err := runner.GetModuleContent(&hclext.BodySchema{
-		Blocks: []hclext.BlockSchema{
-			{
-				Type:       blockType,
-				LabelNames: []string{"type", "name"},
-				Body:       schema,
+// terraform.read_file: content := terraform.read_file(path)
+//
+// Returns the content of a file in the current module directory.
+// This is useful for non-Terraform files such as README.md.
+//
+//	path (string) file path relative to the module directory.
+//
+// Returns:
+//
+//	content (string) file content. Undefined if the file does not exist.
+func ReadFileFunc(runner tflint.Runner) *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.read_file",
+				Decl:             types.NewFunction(types.Args(types.S), types.S),
+				Memoize:          true,
+				Nondeterministic: true,
 			},
 		},
-	}, option.AsGetModuleContentOptions())
-	if err != nil {
-		return nil, err
-	}
+		Impl: func(_ rego.BuiltinContext, pathArg *ast.Term) (*ast.Term, error) {
+			var path string
+			if err := ast.As(pathArg.Value, &path); err != nil {
+				return nil, err
+			}
+			// Files outside the module directory cannot be read.
+			if !filepath.IsLocal(path) {
+				return nil, fmt.Errorf("%s is not a path within the module directory", path)
+			}
 
-	blocks := []*hclext.Block{}
-	for _, block := range content.Blocks {
-		// "*" is a special character that returns all blocks
-		if typeName == block.Labels[0] || typeName == "*" {
-			blocks = append(blocks, block)
-		}
-	}
+			dir, err := moduleDir(runner)
+			if err != nil {
+				return nil, err
+			}
 
-	out, err := typedBlocksToJSON(blocks, tyMap, "schema", runner)
-	if err != nil {
-		return nil, err
-	}
-	v, err := ast.InterfaceToValue(out)
-	if err != nil {
-		return nil, err
-	}
+			// Symbolic links are resolved so that files outside the module directory cannot be read through them.
+			mfs := fileSystem(runner)
+			name, err := mfs.EvalSymlinks(filepath.Join(dir, path))
+			if err != nil {
+				// If the file does not exist, the result is undefined.
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil, nil
+				}
+				return nil, err
+			}
+			resolvedDir, err := mfs.EvalSymlinks(dir)
+			if err != nil {
+				return nil, err
+			}
+			if rel, err := filepath.Rel(resolvedDir, name); err != nil || !filepath.IsLocal(rel) {
+				return nil, fmt.Errorf("%s is not a path within the module directory", path)
+			}
 
-	return ast.NewTerm(v), nil
-}
+			src, err := mfs.ReadFile(name)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil, nil
+				}
+				return nil, err
+			}
 
-func namedBlockFunc(schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
-	var schemaJSON map[string]any
-	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
-		return nil, err
-	}
-	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
-	if err != nil {
-		return nil, err
-	}
-	var optionJSON map[string]string
-	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
-		return nil, err
-	}
-	option, err := jsonToOption(optionJSON)
-	if err != nil {
-		return nil, err
+			return ast.StringTerm(string(src)), nil
+		},
 	}
+}
 
-	content, err := runner.GetModuleContent(&hclext.BodySchema{
-		Blocks: []hclext.BlockSchema{
-			{
-				Type:       blockType,
-				LabelNames: []string{"name"},
-				Body:       schema,
+// terraform.comments: comments := terraform.comments()
+//
+// Returns comments in the current module.
+// Comments in JSON syntax files are not included.
+//
+// Returns:
+//
+//	comments (array[comment]) comments
+func CommentsFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.comments",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, commentTy)),
+				Memoize:          true,
+				Nondeterministic: true,
 			},
 		},
-	}, option.AsGetModuleContentOptions())
-	if err != nil {
-		return nil, err
-	}
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			files, err := runner.GetFiles()
+			if err != nil {
+				return nil, err
+			}
 
-	out, err := namedBlocksToJSON(content.Blocks, tyMap, "schema", runner)
-	if err != nil {
-		return nil, err
-	}
-	v, err := ast.InterfaceToValue(out)
-	if err != nil {
-		return nil, err
-	}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
 
-	return ast.NewTerm(v), nil
-}
+			out := []map[string]any{}
+			for _, name := range names {
+				out = append(out, commentsToJSON(files[name])...)
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
 
-func blockFunc(schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
-	var schemaJSON map[string]any
-	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
-		return nil, err
-	}
-	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
-	if err != nil {
-		return nil, err
-	}
-	var optionJSON map[string]string
-	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
-		return nil, err
-	}
-	option, err := jsonToOption(optionJSON)
-	if err != nil {
-		return nil, err
+			return ast.NewTerm(v), nil
+		},
 	}
+}
 
-	content, err := runner.GetModuleContent(&hclext.BodySchema{
-		Blocks: []hclext.BlockSchema{
-			{
-				Type: blockType,
-				Body: schema,
+// terraform.unformatted_files: files := terraform.unformatted_files()
+//
+// Returns files that are not in the canonical format in the current module.
+// JSON syntax files are not included.
+//
+// Returns:
+//
+//	files (array[unformatted_file]) unformatted files and their diffs
+func UnformattedFilesFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.unformatted_files",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, unformattedFileTy)),
+				Memoize:          true,
+				Nondeterministic: true,
 			},
 		},
-	}, option.AsGetModuleContentOptions())
-	if err != nil {
-		return nil, err
-	}
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			files, err := runner.GetFiles()
+			if err != nil {
+				return nil, err
+			}
 
-	out, err := blocksToJSON(content.Blocks, tyMap, "schema", runner)
-	if err != nil {
-		return nil, err
-	}
-	v, err := ast.InterfaceToValue(out)
-	if err != nil {
-		return nil, err
+			names := make([]string, 0, len(files))
+			for name := range files {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			out := []map[string]any{}
+			for _, name := range names {
+				if strings.HasSuffix(name, ".json") {
+					continue
+				}
+				if file := unformattedFileToJSON(name, files[name]); file != nil {
+					out = append(out, file)
+				}
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
 	}
+}
 
-	return ast.NewTerm(v), nil
+// terraform.lockfile: providers := terraform.lockfile()
+//
+// Returns providers in the dependency lock file (.terraform.lock.hcl) in the current module directory.
+//
+// Returns:
+//
+//	providers (array[lock_provider]) providers in the lock file. Empty if the lock file does not exist.
+func LockfileFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.lockfile",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, lockProviderTy)),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			dir, err := moduleDir(runner)
+			if err != nil {
+				return nil, err
+			}
+
+			filename := filepath.Join(dir, ".terraform.lock.hcl")
+			src, err := fileSystem(runner).ReadFile(filename)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return ast.ArrayTerm(), nil
+				}
+				return nil, err
+			}
+
+			out, err := lockfileToJSON(src, filename)
+			if err != nil {
+				return nil, err
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.tfvars: vars := terraform.tfvars()
+//
+// Returns variable assignments in variable definitions files (*.tfvars and *.tfvars.json)
+// in the current module directory.
+//
+// Returns:
+//
+//	vars (array[tfvar]) variable assignments
+func TfvarsFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.tfvars",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, tfvarTy)),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			dir, err := moduleDir(runner)
+			if err != nil {
+				return nil, err
+			}
+			mfs := fileSystem(runner)
+
+			var names []string
+			for _, pattern := range []string{"*.tfvars", "*.tfvars.json"} {
+				matches, err := mfs.Glob(filepath.Join(dir, pattern))
+				if err != nil {
+					return nil, err
+				}
+				names = append(names, matches...)
+			}
+			sort.Strings(names)
+
+			parser := hclparse.NewParser()
+			out := []map[string]any{}
+			for _, name := range names {
+				src, err := mfs.ReadFile(name)
+				if err != nil {
+					return nil, err
+				}
+
+				var file *hcl.File
+				var diags hcl.Diagnostics
+				if strings.HasSuffix(name, ".json") {
+					file, diags = parser.ParseJSON(src, name)
+				} else {
+					file, diags = parser.ParseHCL(src, name)
+				}
+				if diags.HasErrors() {
+					return nil, diags
+				}
+
+				vars, err := tfvarsToJSON(file)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, vars...)
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.tests: tests := terraform.tests(schema)
+//
+// Returns Terraform test files (*.tftest.hcl and *.tftest.json) in the current module directory
+// and the "tests" directory.
+//
+//	schema (schema) schema for the top-level body of test files.
+//
+// Returns:
+//
+//	tests (array[test_file]) Terraform test files
+func TestsFunc(runner tflint.Runner) *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.tests",
+				Decl:             types.NewFunction(types.Args(schemaTy), types.NewArray(nil, testFileTy)),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, schemaArg *ast.Term) (*ast.Term, error) {
+			var schemaJSON map[string]any
+			if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
+				return nil, err
+			}
+			dir, err := moduleDir(runner)
+			if err != nil {
+				return nil, err
+			}
+			mfs := fileSystem(runner)
+
+			var names []string
+			for _, testDir := range []string{dir, filepath.Join(dir, "tests")} {
+				for _, pattern := range []string{"*.tftest.hcl", "*.tftest.json"} {
+					matches, err := mfs.Glob(filepath.Join(testDir, pattern))
+					if err != nil {
+						return nil, err
+					}
+					names = append(names, matches...)
+				}
+			}
+			sort.Strings(names)
+
+			parser := hclparse.NewParser()
+			testRunner := &staticRunner{Runner: runner, files: map[string]*hcl.File{}}
+			bodies := make([]hcl.Body, len(names))
+			for i, name := range names {
+				src, err := mfs.ReadFile(name)
+				if err != nil {
+					return nil, err
+				}
+
+				var file *hcl.File
+				var diags hcl.Diagnostics
+				if strings.HasSuffix(name, ".json") {
+					file, diags = parser.ParseJSON(src, name)
+				} else {
+					file, diags = parser.ParseHCL(src, name)
+				}
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				testRunner.files[name] = file
+				bodies[i] = file.Body
+			}
+
+			// The schema is applied to the top-level body of test files.
+			schemaJSON, err = expandSchemaWildcard(schemaJSON, discoverSyntaxSchema(bodies), "schema")
+			if err != nil {
+				return nil, err
+			}
+			schema, tyMap, err := jsonToSchema(withTestFileLabels(schemaJSON), map[string]cty.Type{}, "schema")
+			if err != nil {
+				return nil, err
+			}
+
+			contents := make([]*hclext.BodyContent, len(names))
+			for i, body := range bodies {
+				var diags hcl.Diagnostics
+				contents[i], diags = hclext.PartialContent(body, schema)
+				if diags.HasErrors() {
+					return nil, diags
+				}
+			}
+
+			out, err := testFilesToJSON(names, contents, tyMap, testRunner)
+			if err != nil {
+				return nil, err
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.module_manifest: modules := terraform.module_manifest()
+//
+// Returns module calls in the current module with their installation status by "terraform init".
+// The resolved sources are read from the module manifest (.terraform/modules/modules.json).
+//
+// Returns:
+//
+//	modules (array[installed_module]) module calls
+func ModuleManifestFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.module_manifest",
+				Decl:             types.NewFunction(types.Args(), types.NewArray(nil, installedModuleTy)),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			// If the modules are not installed, all module calls are returned as not installed.
+			manifest, _, err := loadModuleManifest(runner)
+			if err != nil {
+				return nil, err
+			}
+
+			path, err := runner.GetModulePath()
+			if err != nil {
+				return nil, err
+			}
+			content, err := runner.GetModuleContent(&hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{
+					{Type: "module", LabelNames: []string{"name"}},
+				},
+			}, &tflint.GetModuleContentOption{ExpandMode: tflint.ExpandModeNone})
+			if err != nil {
+				return nil, err
+			}
+
+			out := installedModulesToJSON(content.Blocks, path, manifest)
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// version_constraint (object<operator: string, version: string>) representation of a single version constraint
+var versionConstraintTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("operator", types.S),
+		types.NewStaticProperty("version", types.S),
+	},
+	nil,
+)
+
+// versionArgTy is a type of version/constraint arguments. This accepts a string or an expr.
+var versionArgTy = types.NewAny(types.S, exprTy)
+
+// versionConstraintRegexp is a pattern for a single constraint, which is the same as hashicorp/go-version.
+var versionConstraintRegexp = regexp.MustCompile(`^\s*(<=|>=|!=|~>|<|>|=|)\s*(\S+)\s*$`)
+
+// terraform.version_constraint_parse: constraints := terraform.version_constraint_parse(constraint)
+//
+// Parses a version constraint string such as "~> 5.0, != 5.3.1".
+// This uses the same semantics as hashicorp/go-version.
+//
+//	constraint (any<string, expr>) version constraint. Undefined if the expr is unknown.
+//
+// Returns:
+//
+//	constraints (array[version_constraint]) constraints separated by commas
+func VersionConstraintParseFunc() *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "terraform.version_constraint_parse",
+				Decl:    types.NewFunction(types.Args(versionArgTy), types.NewArray(nil, versionConstraintTy)),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, constraintArg *ast.Term) (*ast.Term, error) {
+			constraints, known, err := astAsVersionConstraints(constraintArg)
+			if err != nil || !known {
+				return nil, err
+			}
+
+			out := make([]map[string]string, len(constraints))
+			for i, constraint := range constraints {
+				matches := versionConstraintRegexp.FindStringSubmatch(constraint.String())
+				if matches == nil {
+					// should never happen
+					return nil, fmt.Errorf("malformed constraint: %s", constraint)
+				}
+				operator := matches[1]
+				if operator == "" {
+					operator = "="
+				}
+				out[i] = map[string]string{"operator": operator, "version": matches[2]}
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.version_constraint_allows: allowed := terraform.version_constraint_allows(constraint, version)
+//
+// Returns whether the version satisfies the version constraint.
+// This uses the same semantics as hashicorp/go-version.
+//
+//	constraint (any<string, expr>) version constraint. Undefined if the expr is unknown.
+//	version    (any<string, expr>) version. Undefined if the expr is unknown.
+//
+// Returns:
+//
+//	allowed (boolean) true if the version is allowed
+func VersionConstraintAllowsFunc() *Function2 {
+	return &Function2{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "terraform.version_constraint_allows",
+				Decl:    types.NewFunction(types.Args(versionArgTy, versionArgTy), types.B),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, constraintArg *ast.Term, versionArg *ast.Term) (*ast.Term, error) {
+			constraints, known, err := astAsVersionConstraints(constraintArg)
+			if err != nil || !known {
+				return nil, err
+			}
+			str, known, err := astAsVersionString(versionArg)
+			if err != nil || !known {
+				return nil, err
+			}
+			v, err := version.NewVersion(str)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.BooleanTerm(constraints.Check(v)), nil
+		},
+	}
+}
+
+// terraform.version_constraint_pessimistic: pessimistic := terraform.version_constraint_pessimistic(constraint)
+//
+// Returns whether the version constraint uses the pessimistic operator ("~>").
+//
+//	constraint (any<string, expr>) version constraint. Undefined if the expr is unknown.
+//
+// Returns:
+//
+//	pessimistic (boolean) true if any of the constraints is pessimistic
+func VersionConstraintPessimisticFunc() *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "terraform.version_constraint_pessimistic",
+				Decl:    types.NewFunction(types.Args(versionArgTy), types.B),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, constraintArg *ast.Term) (*ast.Term, error) {
+			constraints, known, err := astAsVersionConstraints(constraintArg)
+			if err != nil || !known {
+				return nil, err
+			}
+
+			for _, constraint := range constraints {
+				matches := versionConstraintRegexp.FindStringSubmatch(constraint.String())
+				if matches != nil && matches[1] == "~>" {
+					return ast.BooleanTerm(true), nil
+				}
+			}
+			return ast.BooleanTerm(false), nil
+		},
+	}
+}
+
+func astAsVersionConstraints(v *ast.Term) (version.Constraints, bool, error) {
+	str, known, err := astAsVersionString(v)
+	if err != nil || !known {
+		return nil, known, err
+	}
+	constraints, err := version.NewConstraint(str)
+	if err != nil {
+		return nil, true, err
+	}
+	return constraints, true, nil
+}
+
+// astAsVersionString returns a string from a string or an expr.
+// If the expr has no value (e.g. unknown), it returns false.
+// The value of an unevaluated expr (e.g. the "expr" type) is the raw expression syntax
+// such as `">= 1.5"`, so it is parsed and must be a literal string.
+func astAsVersionString(v *ast.Term) (string, bool, error) {
+	var in any
+	if err := ast.As(v.Value, &in); err != nil {
+		return "", false, err
+	}
+
+	switch cv := in.(type) {
+	case string:
+		return cv, true, nil
+	case map[string]any:
+		value, exists := cv["value"]
+		if !exists {
+			return "", false, nil
+		}
+		str, err := jsonToString(value, "expr.value")
+		if err != nil {
+			return "", false, err
+		}
+		// Evaluated exprs always have "unknown".
+		if _, evaluated := cv["unknown"]; evaluated {
+			return str, true, nil
+		}
+		return rawExprAsString(str)
+	default:
+		return "", false, fmt.Errorf("version is not string or expr, got %T", in)
+	}
+}
+
+// rawExprAsString returns a string from the raw expression syntax such as `">= 1.5"`.
+// Only literal strings are allowed, as the expression cannot be evaluated.
+func rawExprAsString(src string) (string, bool, error) {
+	// The range of a heredoc does not include the newline after the closing marker, which the parser requires.
+	expr, diags := hclsyntax.ParseExpression([]byte(src+"\n"), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false, diags
+	}
+	if len(expr.Variables()) > 0 {
+		return "", false, fmt.Errorf("version must be a literal string, got %s", src)
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || value.Type() != cty.String || value.IsNull() {
+		return "", false, fmt.Errorf("version must be a literal string, got %s", src)
+	}
+	return value.AsString(), true, nil
+}
+
+// terraform.type_constraint: type := terraform.type_constraint(expr)
+//
+// Parses the given type constraint expression such as the "type" attribute of variables.
+// This is equivalent to typeexpr.TypeConstraintWithDefaults in hashicorp/hcl.
+//
+//	expr (raw_expr) type constraint expression which is retrieved as an expr type.
+//
+// Returns:
+//
+//	type (type_constraint) structured representation of the type constraint
+func TypeConstraintFunc() *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "terraform.type_constraint",
+				Decl:    types.NewFunction(types.Args(rawExprTy), typeConstraintTy),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, exprArg *ast.Term) (*ast.Term, error) {
+			expr, _, err := astAsExpr(exprArg)
+			if err != nil {
+				return nil, err
+			}
+
+			ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(expr)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+
+			out, err := typeConstraintToJSON(ty, defaults, expr.Range())
+			if err != nil {
+				return nil, err
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.resource_provider: provider := terraform.resource_provider(resource, schema)
+//
+// Returns the provider configuration used by the given resource.
+// The provider is resolved from the "provider" meta-argument or the resource type,
+// and the configuration passed by the "providers" argument of the calling module.
+// The source address is looked up in required_providers of the module.
+//
+//	resource (typed_block) resource, data source, or ephemeral resource retrieved by other functions.
+//	schema   (schema)      schema for attributes of the provider block.
+//
+// Returns:
+//
+//	provider (resource_provider) provider configuration. Undefined if the resource is not found.
+func ResourceProviderFunc(runner tflint.Runner) *Function2 {
+	return &Function2{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.resource_provider",
+				Decl:             types.NewFunction(types.Args(typedBlockTy, schemaTy), resourceProviderTy),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, resourceArg *ast.Term, schemaArg *ast.Term) (*ast.Term, error) {
+			var resourceJSON map[string]any
+			if err := ast.As(resourceArg.Value, &resourceJSON); err != nil {
+				return nil, err
+			}
+			rng, err := jsonToRange(resourceJSON["decl_range"], "resource.decl_range")
+			if err != nil {
+				return nil, err
+			}
+			var schemaJSON map[string]any
+			if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
+				return nil, err
+			}
+
+			path, err := runner.GetModulePath()
+			if err != nil {
+				return nil, err
+			}
+			name, alias, moduleCtx, found, err := resolveResourceProvider(runner, rng, path)
+			if err != nil || !found {
+				return nil, err
+			}
+			source, err := providerSource(runner, name, moduleCtx)
+			if err != nil {
+				return nil, err
+			}
+
+			// Look up the module where the resource is declared first, and then the calling modules if the provider is inherited.
+			var runnerCtx tflint.Runner = &moduleCtxRunner{Runner: runner, moduleCtx: moduleCtx}
+			block, tyMap, err := findProviderBlockWithSchema(runner, name, alias, schemaJSON, moduleCtx)
+			if err != nil {
+				return nil, err
+			}
+			var unknown bool
+			if block == nil && moduleCtx == tflint.SelfModuleCtxType {
+				block, tyMap, runnerCtx, unknown, err = resolveInheritedProvider(runner, path, name, alias, schemaJSON)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			out, err := resourceProviderToJSON(name, alias, source, block, tyMap, runnerCtx)
+			if err != nil {
+				return nil, err
+			}
+			if unknown {
+				out["unknown"] = true
+			}
+			v, err := ast.InterfaceToValue(out)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.eval: expr := terraform.eval(src)
+//
+// Evaluates an expression in the module context.
+//
+//	src (string) expression to evaluate.
+//
+// Returns:
+//
+//	expr (expr) evaluated expression.
+func EvalFunc(runner tflint.Runner) *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.eval",
+				Decl:             types.NewFunction(types.Args(types.S), exprTy),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, srcArg *ast.Term) (*ast.Term, error) {
+			return evalFunc(srcArg, hcl.Range{Start: hcl.InitialPos, End: hcl.InitialPos}, runner)
+		},
+	}
+}
+
+// terraform.eval_at: expr := terraform.eval_at(src, range)
+//
+// Evaluates an expression in the module context as if it is located at the range.
+//
+//	src   (string) expression to evaluate.
+//	range (range)  range of the expression.
+//
+// Returns:
+//
+//	expr (expr) evaluated expression.
+func EvalAtFunc(runner tflint.Runner) *Function2 {
+	return &Function2{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.eval_at",
+				Decl:             types.NewFunction(types.Args(types.S, rangeTy), exprTy),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, srcArg *ast.Term, rangeArg *ast.Term) (*ast.Term, error) {
+			var rangeJSON any
+			if err := ast.As(rangeArg.Value, &rangeJSON); err != nil {
+				return nil, err
+			}
+			rng, err := jsonToRange(rangeJSON, "range")
+			if err != nil {
+				return nil, err
+			}
+			return evalFunc(srcArg, rng, runner)
+		},
+	}
+}
+
+func evalFunc(srcArg *ast.Term, rng hcl.Range, runner tflint.Runner) (*ast.Term, error) {
+	var src string
+	if err := ast.As(srcArg.Value, &src); err != nil {
+		return nil, err
+	}
+
+	// The source is always HCL native syntax, even if the range points into a JSON file.
+	expr, diags := hclsyntax.ParseExpression([]byte(src), rng.Filename, rng.Start)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	out, err := exprToJSON(expr, map[string]cty.Type{"eval": cty.DynamicPseudoType}, "eval", &evalRunner{Runner: runner})
+	if err != nil {
+		return nil, err
+	}
+	v, err := ast.InterfaceToValue(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(v), nil
+}
+
+// terraform.dependency_graph: graph := terraform.dependency_graph()
+//
+// Returns dependencies between blocks in the current module.
+// Dependencies are detected from references in expressions and "depends_on".
+//
+// Returns:
+//
+//	graph (dependency_graph) dependency graph.
+func DependencyGraphFunc(runner tflint.Runner) *FunctionDyn {
+	return &FunctionDyn{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:             "terraform.dependency_graph",
+				Decl:             types.NewFunction(types.Args(), dependencyGraphTy),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, _ []*ast.Term) (*ast.Term, error) {
+			files, err := runner.GetFiles()
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := ast.InterfaceToValue(dependencyGraphToJSON(files))
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+// terraform.fixture: sources := terraform.fixture(dir)
+//
+// Returns the sources of a test fixture directory, which can be passed to
+// mock functions and terraform.module.
+//
+//	dir (string) directory path relative to the policy file.
+//
+// Returns:
+//
+//	sources (object[string: string]) sources of .tf, .tf.json, .tfvars, and .tfvars.json files in the directory
+//
+// Errors halt the evaluation so that a missing fixture is reported as a test error
+// rather than an undefined value.
+func FixtureFunc() *Function1 {
+	return &Function1{
+		Function: Function{
+			Decl: &rego.Function{
+				Name: "terraform.fixture",
+				Decl: types.NewFunction(
+					types.Args(types.S),
+					types.NewObject(nil, types.NewDynamicProperty(types.S, types.S)),
+				),
+				Memoize:          true,
+				Nondeterministic: true,
+			},
+		},
+		Impl: func(ctx rego.BuiltinContext, dirArg *ast.Term) (*ast.Term, error) {
+			var dir string
+			if err := ast.As(dirArg.Value, &dir); err != nil {
+				return nil, err
+			}
+			// Relative paths are resolved from the directory of the policy file
+			if !filepath.IsAbs(dir) && ctx.Location != nil && ctx.Location.File != "" {
+				dir = filepath.Join(filepath.Dir(ctx.Location.File), dir)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil, rego.NewHaltError(fmt.Errorf("fixture directory %s does not exist", dir))
+				}
+				return nil, rego.NewHaltError(fmt.Errorf("failed to read fixture directory %s: %w", dir, err))
+			}
+
+			sources := map[string]string{}
+			for _, entry := range entries {
+				if entry.IsDir() || !isFixtureFile(entry.Name()) {
+					continue
+				}
+
+				src, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				if err != nil {
+					return nil, rego.NewHaltError(err)
+				}
+				sources[entry.Name()] = string(src)
+			}
+
+			v, err := ast.InterfaceToValue(sources)
+			if err != nil {
+				return nil, err
+			}
+			return ast.NewTerm(v), nil
+		},
+	}
+}
+
+func isFixtureFile(name string) bool {
+	for _, ext := range []string{".tf", ".tf.json", ".tfvars", ".tfvars.json"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func typedBlockFunc(typeArg *ast.Term, schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
+	var typeName string
+	if err := ast.As(typeArg.Value, &typeName); err != nil {
+		return nil, err
+	}
+	var schemaJSON map[string]any
+	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
+		return nil, err
+	}
+	var optionJSON map[string]string
+	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
+		return nil, err
+	}
+	option, err := jsonToOption(optionJSON)
+	if err != nil {
+		return nil, err
+	}
+	schemaJSON, err = expandSchema(schemaJSON, runner, option.ModuleCtx, schemaTarget{path: []hcl.BlockHeaderSchema{{Type: blockType, LabelNames: []string{"type", "name"}}}, name: typeName})
+	if err != nil {
+		return nil, err
+	}
+	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+	if err != nil {
+		return nil, err
+	}
+	schema = option.Schema(schema, tyMap)
+
+	content, err := runner.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type:       blockType,
+				LabelNames: []string{"type", "name"},
+				Body:       schema,
+			},
+		},
+	}, option.AsGetModuleContentOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []*hclext.Block{}
+	for _, block := range content.Blocks {
+		// "*" is a special character that returns all blocks
+		if typeName == block.Labels[0] || typeName == "*" {
+			blocks = append(blocks, block)
+		}
+	}
+
+	out, err := typedBlocksToJSON(blocks, tyMap, "schema", option.Runner(runner))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ast.InterfaceToValue(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(v), nil
+}
+
+func namedBlockFunc(schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
+	var schemaJSON map[string]any
+	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
+		return nil, err
+	}
+	var optionJSON map[string]string
+	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
+		return nil, err
+	}
+	option, err := jsonToOption(optionJSON)
+	if err != nil {
+		return nil, err
+	}
+	schemaJSON, err = expandSchema(schemaJSON, runner, option.ModuleCtx, schemaTarget{path: []hcl.BlockHeaderSchema{{Type: blockType, LabelNames: []string{"name"}}}})
+	if err != nil {
+		return nil, err
+	}
+	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+	if err != nil {
+		return nil, err
+	}
+	schema = option.Schema(schema, tyMap)
+
+	content, err := runner.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type:       blockType,
+				LabelNames: []string{"name"},
+				Body:       schema,
+			},
+		},
+	}, option.AsGetModuleContentOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := namedBlocksToJSON(content.Blocks, tyMap, "schema", option.Runner(runner))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ast.InterfaceToValue(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(v), nil
+}
+
+func blockFunc(schemaArg *ast.Term, optionArg *ast.Term, blockType string, runner tflint.Runner) (*ast.Term, error) {
+	var schemaJSON map[string]any
+	if err := ast.As(schemaArg.Value, &schemaJSON); err != nil {
+		return nil, err
+	}
+	var optionJSON map[string]string
+	if err := ast.As(optionArg.Value, &optionJSON); err != nil {
+		return nil, err
+	}
+	option, err := jsonToOption(optionJSON)
+	if err != nil {
+		return nil, err
+	}
+	schemaJSON, err = expandSchema(schemaJSON, runner, option.ModuleCtx, schemaTarget{path: []hcl.BlockHeaderSchema{{Type: blockType}}})
+	if err != nil {
+		return nil, err
+	}
+	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+	if err != nil {
+		return nil, err
+	}
+	schema = option.Schema(schema, tyMap)
+
+	content, err := runner.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type: blockType,
+				Body: schema,
+			},
+		},
+	}, option.AsGetModuleContentOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := blocksToJSON(content.Blocks, tyMap, "schema", option.Runner(runner))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ast.InterfaceToValue(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(v), nil
+}
+
+// moduleDir returns the directory of the current module.
+// If the runner knows the module directory, such as the test runner, it is used.
+// Otherwise, the directory is determined by the first file in lexical order, so the result
+// does not depend on the map iteration order. If there is no file, the current directory is assumed.
+func moduleDir(runner tflint.Runner) (string, error) {
+	if r, ok := runner.(moduleDirRunner); ok {
+		return r.ModuleDir(), nil
+	}
+
+	files, err := runner.GetFiles()
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return ".", nil
+	}
+	sort.Strings(names)
+	return filepath.Dir(names[0]), nil
+}
+
+// moduleDirRunner is an interface for runners that know the module directory.
+// The test runner satisfies this interface, as mock files can be placed in multiple directories.
+type moduleDirRunner interface {
+	ModuleDir() string
+}
+
+// moduleFS is an interface for reading files other than Terraform config files.
+// The test runner satisfies this interface to read in-memory files.
+type moduleFS interface {
+	ReadFile(name string) ([]byte, error)
+	Glob(pattern string) ([]string, error)
+	EvalSymlinks(name string) (string, error)
+}
+
+// osFS reads files from the local filesystem.
+type osFS struct{}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+func (osFS) EvalSymlinks(name string) (string, error) {
+	return filepath.EvalSymlinks(name)
+}
+
+// fileSystem returns moduleFS for the runner.
+// If the runner cannot read files by itself, the local filesystem is used.
+func fileSystem(runner tflint.Runner) moduleFS {
+	if mfs, ok := runner.(moduleFS); ok {
+		return mfs
+	}
+	return osFS{}
+}
+
+// staticRunner evaluates expressions in files that are not part of the module, such as test files.
+// Expressions are evaluated without the module context, so all references and function calls are unknown.
+type staticRunner struct {
+	tflint.Runner
+
+	files map[string]*hcl.File
+}
+
+func (r *staticRunner) GetFile(name string) (*hcl.File, error) {
+	if file, exists := r.files[name]; exists {
+		return file, nil
+	}
+	return r.Runner.GetFile(name)
+}
+
+func (r *staticRunner) EvaluateExpr(expr hcl.Expression, target any, _ *tflint.EvaluateExprOption) error {
+	ret, ok := target.(*cty.Value)
+	if !ok {
+		return fmt.Errorf("unsupported target type: %T", target)
+	}
+
+	ctx := &hcl.EvalContext{Variables: map[string]cty.Value{}}
+	for _, traversal := range expr.Variables() {
+		ctx.Variables[traversal.RootName()] = cty.DynamicVal
+	}
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		value = cty.DynamicVal
+	}
+
+	*ret = value
+	return nil
+}
+
+// evalRunner evaluates expressions that are not part of the module files.
+// TFLint only evaluates expressions in the module files, as the plugin sends the range of
+// the expression, not the expression itself. So references are resolved by TFLint, and the rest
+// of the expression, including function calls, is evaluated with the same functions as TFLint:
+//
+//   - Local values are resolved by evaluating their definitions.
+//   - Other references are resolved by evaluating the same references in the module files.
+//   - path.* and terraform.workspace that do not appear in the module files are determined
+//     in the same way as TFLint.
+//   - Input variables that are not referenced in the module files cannot be resolved, which is an error.
+//     References to resources, data sources, and modules that do not appear are unknown, as in TFLint.
+//
+// References to names bound in their scope, such as for expression variables and
+// dynamic block iterators, are never used, and "count", "each", and "self" are always unknown.
+type evalRunner struct {
+	tflint.Runner
+}
+
+// moduleFunctions is an interface for Terraform functions to evaluate expressions.
+// The test runner satisfies this interface to make the functions deterministic.
+type moduleFunctions interface {
+	Functions(exprs ...hcl.Expression) map[string]function.Function
+}
+
+func (r *evalRunner) EvaluateExpr(expr hcl.Expression, target any, opts *tflint.EvaluateExprOption) error {
+	ret, ok := target.(*cty.Value)
+	if !ok {
+		return fmt.Errorf("unsupported target type: %T", target)
+	}
+
+	variables, err := r.resolveReferences(expr.Variables(), opts)
+	if err != nil {
+		return err
+	}
+	var functions map[string]function.Function
+	if mf, ok := r.Runner.(moduleFunctions); ok {
+		functions = mf.Functions(expr)
+	} else {
+		functions = lang.Functions(expr)
+	}
+	value, diags := expr.Value(&hcl.EvalContext{Variables: variables, Functions: functions})
+	if diags.HasErrors() {
+		return diags
+	}
+
+	*ret = value
+	return nil
+}
+
+func (r *evalRunner) resolveReferences(traversals []hcl.Traversal, opts *tflint.EvaluateExprOption) (map[string]cty.Value, error) {
+	if len(traversals) == 0 {
+		return map[string]cty.Value{}, nil
+	}
+
+	content, err := r.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{Type: "locals", Body: &hclext.BodySchema{Mode: hclext.SchemaJustAttributesMode}},
+		},
+	}, &tflint.GetModuleContentOption{ExpandMode: tflint.ExpandModeNone})
+	if err != nil {
+		return nil, err
+	}
+	locals := map[string]hcl.Expression{}
+	for _, block := range content.Blocks {
+		for name, attr := range block.Body.Attributes {
+			locals[name] = attr.Expr
+		}
+	}
+	references, err := r.moduleReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]any{}
+	for _, traversal := range traversals {
+		addr := referenceAddr(traversal)
+		if contextualReference(addr[0]) || len(addr) == 1 {
+			// References only available in specific blocks and whole objects such as var[local.name] are unknown.
+			insertReference(tree, addr, cty.DynamicVal)
+			continue
+		}
+
+		var value cty.Value
+		switch target, exists := references[strings.Join(addr, ".")]; {
+		case addr[0] == "local":
+			def, exists := locals[addr[1]]
+			if !exists {
+				return nil, fmt.Errorf("%s: local value %q is not declared", traversal.SourceRange(), addr[1])
+			}
+			if value, err = r.evaluate(def, opts); err != nil {
+				return nil, err
+			}
+		case exists:
+			if value, err = r.evaluate(target, opts); err != nil {
+				return nil, err
+			}
+		case addr[0] == "path" || addr[0] == "terraform":
+			if value, err = r.builtinReference(addr, traversal.SourceRange()); err != nil {
+				return nil, err
+			}
+		case addr[0] == "var":
+			return nil, fmt.Errorf("%s: var.%s cannot be evaluated because it is not referenced in the module", traversal.SourceRange(), addr[1])
+		default:
+			// Resources, data sources, and modules are unknown in TFLint
+			value = cty.DynamicVal
+		}
+		insertReference(tree, addr, value)
+	}
+
+	return referenceTreeToValues(tree), nil
+}
+
+// builtinReference returns the value of path.* or terraform.workspace in the same way as TFLint.
+func (r *evalRunner) builtinReference(addr []string, rng hcl.Range) (cty.Value, error) {
+	switch strings.Join(addr, ".") {
+	case "path.module":
+		dir, err := moduleDir(r)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return cty.StringVal(filepath.ToSlash(dir)), nil
+	case "path.root":
+		path, err := r.GetModulePath()
+		if err != nil {
+			return cty.NilVal, err
+		}
+		if !path.IsRoot() {
+			// TFLint inspects modules in the working directory, which is the root module.
+			return cty.StringVal("."), nil
+		}
+		dir, err := moduleDir(r)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return cty.StringVal(filepath.ToSlash(dir)), nil
+	case "path.cwd":
+		wd, err := r.GetOriginalwd()
+		if err != nil {
+			return cty.NilVal, err
+		}
+		wd, err = filepath.Abs(wd)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return cty.StringVal(filepath.ToSlash(wd)), nil
+	case "terraform.workspace":
+		return cty.StringVal(terraform.Workspace()), nil
+	default:
+		return cty.NilVal, fmt.Errorf("%s: unsupported reference %s", rng, strings.Join(addr, "."))
+	}
+}
+
+// evaluate evaluates the expression in the module with TFLint.
+// If the expression is nil, the value is unknown.
+func (r *evalRunner) evaluate(expr hcl.Expression, opts *tflint.EvaluateExprOption) (cty.Value, error) {
+	if expr == nil {
+		return cty.DynamicVal, nil
+	}
+
+	var value cty.Value
+	if err := r.Runner.EvaluateExpr(expr, &value, opts); err != nil {
+		if !errors.Is(err, tflint.ErrSensitive) {
+			return cty.NilVal, err
+		}
+		value = cty.DynamicVal.Mark(marks.Sensitive)
+	}
+	return value, nil
+}
+
+// moduleReferences returns references in the module files that TFLint can evaluate at their ranges,
+// keyed by their addresses. Only the address part of the traversal is returned, e.g. var.foo for var.foo.bar.
+// References that are not evaluated by Terraform, such as "depends_on", and references
+// to names bound in their scope are excluded. JSON files are not supported.
+func (r *evalRunner) moduleReferences() (map[string]hcl.Expression, error) {
+	files, err := r.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := map[string]hcl.Expression{}
+	for _, name := range names {
+		body, ok := files[name].Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+		walkEvaluableExprs(body, "", map[string]bool{}, func(expr hclsyntax.Expression, bound map[string]bool) {
+			for _, traversal := range scopedReferences(expr, bound) {
+				addr := referenceAddr(traversal)
+				key := strings.Join(addr, ".")
+				if _, exists := ret[key]; exists {
+					continue
+				}
+				traversal = traversal[:len(addr)]
+				ret[key] = &hclsyntax.ScopeTraversalExpr{
+					Traversal: traversal,
+					SrcRange:  hcl.RangeBetween(traversal[0].SourceRange(), traversal[len(traversal)-1].SourceRange()),
+				}
+			}
+		})
+	}
+	return ret, nil
+}
+
+// staticAttributes are attributes that Terraform does not evaluate as expressions, by block type.
+// Attributes for "" are static in all blocks.
+var staticAttributes = map[string][]string{
+	"":          {"depends_on", "provider", "providers"},
+	"lifecycle": {"ignore_changes", "replace_triggered_by"},
+	"variable":  {"type"},
+	"moved":     {"from", "to"},
+	"removed":   {"from"},
+	"import":    {"to"},
+}
+
+// walkEvaluableExprs calls the function with attribute expressions in the body that Terraform evaluates,
+// and names bound in their scope by dynamic block iterators.
+func walkEvaluableExprs(body *hclsyntax.Body, blockType string, bound map[string]bool, fn func(hclsyntax.Expression, map[string]bool)) {
+	names := make([]string, 0, len(body.Attributes))
+	for name := range body.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if slices.Contains(staticAttributes[""], name) || slices.Contains(staticAttributes[blockType], name) {
+			continue
+		}
+		fn(body.Attributes[name].Expr, bound)
+	}
+
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "terraform":
+			// The terraform block has no expressions evaluated in the module context.
+		case "dynamic":
+			if len(block.Labels) == 0 {
+				continue
+			}
+			// The iterator is bound in "labels" and "content", but not in "for_each".
+			iterator := block.Labels[0]
+			if attr, exists := block.Body.Attributes["iterator"]; exists {
+				iterator = hcl.ExprAsKeyword(attr.Expr)
+			}
+			scope := withBoundName(bound, iterator)
+			if attr, exists := block.Body.Attributes["for_each"]; exists {
+				fn(attr.Expr, bound)
+			}
+			if attr, exists := block.Body.Attributes["labels"]; exists {
+				fn(attr.Expr, scope)
+			}
+			for _, content := range block.Body.Blocks {
+				if content.Type == "content" {
+					walkEvaluableExprs(content.Body, block.Labels[0], scope, fn)
+				}
+			}
+		default:
+			walkEvaluableExprs(block.Body, block.Type, bound, fn)
+		}
+	}
+}
+
+func withBoundName(bound map[string]bool, name string) map[string]bool {
+	ret := make(map[string]bool, len(bound)+1)
+	for k, v := range bound {
+		ret[k] = v
+	}
+	if name != "" {
+		ret[name] = true
+	}
+	return ret
+}
+
+// scopedReferences returns traversals in the expression that refer to names not bound in their scope.
+// Object keys of bare names are excluded, as they are not references even though they are traversals.
+func scopedReferences(expr hclsyntax.Expression, bound map[string]bool) []hcl.Traversal {
+	skipped := map[hclsyntax.Node]bool{}
+	scoped := map[hclsyntax.Node][]string{}
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		switch node := node.(type) {
+		case *hclsyntax.ObjectConsKeyExpr:
+			if !node.ForceNonLiteral && hcl.ExprAsKeyword(node.Wrapped) != "" {
+				skipSubtree(node, skipped)
+			}
+		case *hclsyntax.ForExpr:
+			for _, scopedExpr := range []hclsyntax.Expression{node.KeyExpr, node.ValExpr, node.CondExpr} {
+				if scopedExpr == nil {
+					continue
+				}
+				hclsyntax.VisitAll(scopedExpr, func(child hclsyntax.Node) hcl.Diagnostics {
+					scoped[child] = append(scoped[child], node.KeyVar, node.ValVar)
+					return nil
+				})
+			}
+		}
+		return nil
+	})
+
+	ret := []hcl.Traversal{}
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || skipped[node] {
+			return nil
+		}
+		name := expr.Traversal.RootName()
+		if bound[name] || contextualReference(name) || slices.Contains(scoped[node], name) {
+			return nil
+		}
+		ret = append(ret, expr.Traversal)
+		return nil
+	})
+	return ret
+}
+
+func skipSubtree(node hclsyntax.Node, skipped map[hclsyntax.Node]bool) {
+	hclsyntax.VisitAll(node, func(child hclsyntax.Node) hcl.Diagnostics {
+		skipped[child] = true
+		return nil
+	})
+}
+
+// contextualReference returns whether the name refers to values only available in specific blocks.
+func contextualReference(name string) bool {
+	return name == "count" || name == "each" || name == "self"
+}
+
+// insertReference inserts the value into the tree of references at the address.
+// If a parent of the address is already inserted as a whole, such as an unknown object, it takes precedence.
+func insertReference(tree map[string]any, addr []string, value cty.Value) {
+	node := tree
+	for _, name := range addr[:len(addr)-1] {
+		switch child := node[name].(type) {
+		case map[string]any:
+			node = child
+		case cty.Value:
+			return
+		default:
+			next := map[string]any{}
+			node[name] = next
+			node = next
+		}
+	}
+	node[addr[len(addr)-1]] = value
+}
+
+// referenceAddr returns the names of the referenced address in the traversal.
+// Indexes with string keys are the same as attributes, e.g. var["foo"] => ["var", "foo"].
+// e.g. var.foo.bar => ["var", "foo"], data.aws_ami.main.id => ["data", "aws_ami", "main"]
+func referenceAddr(traversal hcl.Traversal) []string {
+	size := 2
+	if traversal.RootName() == "data" {
+		size = 3
+	}
+
+	addr := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		if len(addr) == size {
+			break
+		}
+		var name string
+		switch step := step.(type) {
+		case hcl.TraverseAttr:
+			name = step.Name
+		case hcl.TraverseIndex:
+			if step.Key.Type() == cty.String && step.Key.IsKnown() && !step.Key.IsNull() {
+				name = step.Key.AsString()
+			}
+		}
+		if name == "" {
+			break
+		}
+		addr = append(addr, name)
+	}
+	return addr
+}
+
+func referenceTreeToValues(tree map[string]any) map[string]cty.Value {
+	ret := map[string]cty.Value{}
+	for name, node := range tree {
+		switch node := node.(type) {
+		case cty.Value:
+			ret[name] = node
+		case map[string]any:
+			ret[name] = cty.ObjectVal(referenceTreeToValues(node))
+		}
+	}
+	return ret
+}
+
+// resolveResourceProvider returns the provider local name and alias used by the resource declared at the range,
+// and the module context in which the resource is declared. The current module is looked up first,
+// and then the root module. If the resource is not found, it returns false.
+func resolveResourceProvider(runner tflint.Runner, rng hcl.Range, path addrs.Module) (string, string, tflint.ModuleCtxType, bool, error) {
+	moduleCtxs := []tflint.ModuleCtxType{tflint.SelfModuleCtxType}
+	if !path.IsRoot() {
+		moduleCtxs = append(moduleCtxs, tflint.RootModuleCtxType)
+	}
+
+	for _, moduleCtx := range moduleCtxs {
+		content, err := runner.GetModuleContent(&hclext.BodySchema{
+			Blocks: []hclext.BlockSchema{
+				{Type: "resource", LabelNames: []string{"type", "name"}, Body: providerMetaArgSchema},
+				{Type: "data", LabelNames: []string{"type", "name"}, Body: providerMetaArgSchema},
+				{Type: "ephemeral", LabelNames: []string{"type", "name"}, Body: providerMetaArgSchema},
+			},
+		}, &tflint.GetModuleContentOption{ModuleCtx: moduleCtx, ExpandMode: tflint.ExpandModeNone})
+		if err != nil {
+			return "", "", moduleCtx, false, err
+		}
+
+		for _, resource := range content.Blocks {
+			if resource.DefRange.Filename != rng.Filename || resource.DefRange.Start.Byte != rng.Start.Byte {
+				continue
+			}
+
+			if attr, exists := resource.Body.Attributes["provider"]; exists {
+				name, alias, err := providerAddr(attr.Expr)
+				return name, alias, moduleCtx, true, err
+			}
+			// The default provider is determined by the prefix of the resource type.
+			return strings.SplitN(resource.Labels[0], "_", 2)[0], "", moduleCtx, true, nil
+		}
+	}
+
+	return "", "", tflint.SelfModuleCtxType, false, nil
+}
+
+var requiredProvidersSchema = &hclext.BodySchema{
+	Blocks: []hclext.BlockSchema{
+		{
+			Type: "terraform",
+			Body: &hclext.BodySchema{
+				Blocks: []hclext.BlockSchema{{Type: "required_providers", Body: &hclext.BodySchema{Mode: hclext.SchemaJustAttributesMode}}},
+			},
+		},
+	},
+}
+
+// providerSource returns the source address of the provider with the local name, such as
+// "registry.terraform.io/hashicorp/google-beta" for google = { source = "hashicorp/google-beta" }.
+// Like Terraform, required_providers of the module is looked up first, and if the local name
+// is not declared, the provider is assumed to be in the "hashicorp" namespace.
+func providerSource(runner tflint.Runner, name string, moduleCtx tflint.ModuleCtxType) (string, error) {
+	content, err := runner.GetModuleContent(requiredProvidersSchema, &tflint.GetModuleContentOption{ModuleCtx: moduleCtx, ExpandMode: tflint.ExpandModeNone})
+	if err != nil {
+		return "", err
+	}
+
+	for _, terraform := range content.Blocks {
+		for _, requiredProviders := range terraform.Body.Blocks {
+			attr, exists := requiredProviders.Body.Attributes[name]
+			if !exists {
+				continue
+			}
+			// The requirement can include references such as configuration_aliases,
+			// so only the source attribute is evaluated.
+			pairs, diags := hcl.ExprMap(attr.Expr)
+			if diags.HasErrors() {
+				// Legacy version constraints such as aws = "~> 5.0" have no source.
+				continue
+			}
+			for _, pair := range pairs {
+				if hcl.ExprAsKeyword(pair.Key) != "source" {
+					continue
+				}
+				source, diags := pair.Value.Value(nil)
+				if diags.HasErrors() {
+					return "", diags
+				}
+				if source.Type() != cty.String || source.IsNull() || !source.IsKnown() {
+					return "", fmt.Errorf("%s: source must be a string", pair.Value.Range())
+				}
+				return normalizeProviderSource(source.AsString()), nil
+			}
+		}
+	}
+
+	return normalizeProviderSource("hashicorp/" + name), nil
+}
+
+// normalizeProviderSource returns the fully-qualified provider source address,
+// like addresses in the dependency lock file. The public registry is assumed if the hostname is omitted.
+func normalizeProviderSource(source string) string {
+	source = strings.ToLower(source)
+	switch strings.Count(source, "/") {
+	case 0:
+		return "registry.terraform.io/hashicorp/" + source
+	case 1:
+		return "registry.terraform.io/" + source
+	default:
+		return source
+	}
+}
+
+var moduleProvidersSchema = &hclext.BodySchema{
+	Blocks: []hclext.BlockSchema{
+		{
+			Type:       "module",
+			LabelNames: []string{"name"},
+			Body:       &hclext.BodySchema{Attributes: []hclext.AttributeSchema{{Name: "providers"}}},
+		},
+	},
+}
+
+// resolveInheritedProvider walks the module calls from the current module up to the root module,
+// and returns the provider block passed to the current module through the chain.
+// The root module is read through the runner, and intermediate modules are read from the directories
+// recorded in the module manifest, with their expressions evaluated statically.
+// If the provider is not passed to the current module, the block is nil.
+// If an intermediate module is not installed, it returns unknown.
+func resolveInheritedProvider(runner tflint.Runner, path addrs.Module, name string, alias string, schemaJSON map[string]any) (*hclext.Block, map[string]cty.Type, tflint.Runner, bool, error) {
+	var manifest moduleManifest
+	var installed bool
+
+	for i := len(path) - 1; i >= 0; i-- {
+		if i == 0 {
+			content, err := runner.GetModuleContent(moduleProvidersSchema, &tflint.GetModuleContentOption{ModuleCtx: tflint.RootModuleCtxType, ExpandMode: tflint.ExpandModeNone})
+			if err != nil {
+				return nil, nil, runner, false, err
+			}
+			parentName, parentAlias, inherited, err := inheritedProvider(content.Blocks, path[i], name, alias)
+			if err != nil || !inherited {
+				return nil, nil, runner, false, err
+			}
+
+			block, tyMap, err := findProviderBlockWithSchema(runner, parentName, parentAlias, schemaJSON, tflint.RootModuleCtxType)
+			return block, tyMap, &moduleCtxRunner{Runner: runner, moduleCtx: tflint.RootModuleCtxType}, false, err
+		}
+
+		if !installed {
+			var err error
+			manifest, installed, err = loadModuleManifest(runner)
+			if err != nil {
+				return nil, nil, runner, false, err
+			}
+			if !installed {
+				return nil, nil, runner, true, nil
+			}
+		}
+		files, found, err := installedModuleFiles(runner, manifest, path[:i])
+		if err != nil {
+			return nil, nil, runner, false, err
+		}
+		if !found {
+			return nil, nil, runner, true, nil
+		}
+
+		var calls hclext.Blocks
+		for _, file := range files {
+			content, diags := hclext.PartialContent(file.Body, moduleProvidersSchema)
+			if diags.HasErrors() {
+				return nil, nil, runner, false, diags
+			}
+			calls = append(calls, content.Blocks...)
+		}
+		parentName, parentAlias, inherited, err := inheritedProvider(calls, path[i], name, alias)
+		if err != nil || !inherited {
+			return nil, nil, runner, false, err
+		}
+		name, alias = parentName, parentAlias
+
+		block, tyMap, err := findStaticProviderBlock(files, name, alias, schemaJSON)
+		if err != nil || block != nil {
+			return block, tyMap, &staticRunner{Runner: runner, files: files}, false, err
+		}
+	}
+
+	return nil, nil, runner, false, nil
+}
+
+// inheritedProvider returns the provider in the calling module that is passed to the module call
+// of the given name. If the provider is not passed, it returns false.
+func inheritedProvider(calls hclext.Blocks, call string, name string, alias string) (string, string, bool, error) {
+	for _, module := range calls {
+		if module.Labels[0] != call {
+			continue
+		}
+
+		attr, exists := module.Body.Attributes["providers"]
+		if !exists {
+			// Default providers are inherited implicitly if "providers" is not set.
+			return name, "", alias == "", nil
+		}
+
+		pairs, diags := hcl.ExprMap(attr.Expr)
+		if diags.HasErrors() {
+			return "", "", false, diags
+		}
+		for _, pair := range pairs {
+			childName, childAlias, err := providerAddr(pair.Key)
+			if err != nil {
+				return "", "", false, err
+			}
+			if childName != name || childAlias != alias {
+				continue
+			}
+
+			parentName, parentAlias, err := providerAddr(pair.Value)
+			return parentName, parentAlias, true, err
+		}
+	}
+
+	return "", "", false, nil
+}
+
+// loadModuleManifest reads the module manifest (.terraform/modules/modules.json).
+// If the modules are not installed, it returns false.
+func loadModuleManifest(runner tflint.Runner) (moduleManifest, bool, error) {
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	filename := filepath.Join(dataDir, "modules", "modules.json")
+
+	var manifest moduleManifest
+	src, err := fileSystem(runner).ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return manifest, false, nil
+		}
+		return manifest, false, err
+	}
+	if err := json.Unmarshal(src, &manifest); err != nil {
+		return manifest, false, fmt.Errorf("failed to parse %s; %w", filename, err)
+	}
+	return manifest, true, nil
+}
+
+// installedModuleFiles parses Terraform config files of the installed module at the path.
+// If the module is not found in the manifest, it returns false.
+func installedModuleFiles(runner tflint.Runner, manifest moduleManifest, path addrs.Module) (map[string]*hcl.File, bool, error) {
+	key := strings.Join(path, ".")
+	var dir string
+	var found bool
+	for _, record := range manifest.Modules {
+		if record.Key == key {
+			dir, found = record.Dir, true
+			break
+		}
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	mfs := fileSystem(runner)
+	var names []string
+	for _, pattern := range []string{"*.tf", "*.tf.json"} {
+		matches, err := mfs.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, false, err
+		}
+		names = append(names, matches...)
+	}
+	sort.Strings(names)
+
+	parser := hclparse.NewParser()
+	files := make(map[string]*hcl.File, len(names))
+	for _, name := range names {
+		src, err := mfs.ReadFile(name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(name, ".json") {
+			file, diags = parser.ParseJSON(src, name)
+		} else {
+			file, diags = parser.ParseHCL(src, name)
+		}
+		if diags.HasErrors() {
+			return nil, false, diags
+		}
+		files[name] = file
+	}
+	return files, true, nil
+}
+
+// findStaticProviderBlock finds the provider block in the parsed files with the schema.
+// This is the same as findProviderBlockWithSchema, but for modules that are not accessible through the runner.
+func findStaticProviderBlock(files map[string]*hcl.File, name string, alias string, schemaJSON map[string]any) (*hclext.Block, map[string]cty.Type, error) {
+	names := make([]string, 0, len(files))
+	for filename := range files {
+		names = append(names, filename)
+	}
+	sort.Strings(names)
+
+	var bodies []hcl.Body
+	for _, filename := range names {
+		content, _, diags := files[filename].Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "provider", LabelNames: []string{"name"}}},
+		})
+		if diags.HasErrors() {
+			return nil, nil, diags
+		}
+		for _, block := range content.Blocks {
+			if block.Labels[0] == name {
+				bodies = append(bodies, block.Body)
+			}
+		}
+	}
+	if len(bodies) == 0 {
+		return nil, nil, nil
+	}
+
+	schemaJSON, err := expandSchemaWildcard(schemaJSON, discoverSyntaxSchema(bodies), "schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	schema = withDynamicBlockSchema(schema, tyMap, "schema")
+	schema = mergeSchema(schema, &hclext.BodySchema{Attributes: []hclext.AttributeSchema{{Name: "alias"}}})
+	providerSchema := &hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{{Type: "provider", LabelNames: []string{"name"}, Body: schema}},
+	}
+
+	for _, filename := range names {
+		content, diags := hclext.PartialContent(files[filename].Body, providerSchema)
+		if diags.HasErrors() {
+			return nil, nil, diags
+		}
+		for _, provider := range content.Blocks {
+			if provider.Labels[0] != name {
+				continue
+			}
+			var providerAlias string
+			if attr, exists := provider.Body.Attributes["alias"]; exists {
+				if diags := gohcl.DecodeExpression(attr.Expr, nil, &providerAlias); diags.HasErrors() {
+					return nil, nil, diags
+				}
+			}
+			if providerAlias == alias {
+				return provider, tyMap, nil
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// findProviderBlockWithSchema finds the provider block in the module context with the schema.
+// Wildcards in the schema are expanded with the provider blocks of the same name.
+func findProviderBlockWithSchema(runner tflint.Runner, name string, alias string, schemaJSON map[string]any, moduleCtx tflint.ModuleCtxType) (*hclext.Block, map[string]cty.Type, error) {
+	schemaJSON, err := expandSchema(schemaJSON, runner, moduleCtx, schemaTarget{path: []hcl.BlockHeaderSchema{{Type: "provider", LabelNames: []string{"name"}}}, name: name})
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, tyMap, err := jsonToSchema(schemaJSON, map[string]cty.Type{}, "schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	// Provider blocks are never expanded, so templates of dynamic blocks are retrieved as well.
+	schema = withDynamicBlockSchema(schema, tyMap, "schema")
+
+	block, err := findProviderBlock(runner, name, alias, schema, moduleCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, tyMap, nil
+}
+
+// findProviderBlock returns the provider block that matches the name and alias.
+// If not found, it returns nil.
+func findProviderBlock(runner tflint.Runner, name string, alias string, schema *hclext.BodySchema, moduleCtx tflint.ModuleCtxType) (*hclext.Block, error) {
+	// The "alias" is always retrieved to find the provider.
+	schema = mergeSchema(schema, &hclext.BodySchema{Attributes: []hclext.AttributeSchema{{Name: "alias"}}})
+
+	content, err := runner.GetProviderContent(name, schema, &tflint.GetModuleContentOption{ModuleCtx: moduleCtx, ExpandMode: tflint.ExpandModeNone})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, provider := range content.Blocks {
+		var providerAlias string
+		if attr, exists := provider.Body.Attributes["alias"]; exists {
+			if diags := gohcl.DecodeExpression(attr.Expr, nil, &providerAlias); diags.HasErrors() {
+				return nil, diags
+			}
+		}
+		if providerAlias == alias {
+			return provider, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// providerAddr returns the provider local name and alias from a reference such as "aws.west".
+func providerAddr(expr hcl.Expression) (string, string, error) {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() {
+		return "", "", diags
+	}
+
+	switch len(traversal) {
+	case 1:
+		return traversal.RootName(), "", nil
+	case 2:
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+			return traversal.RootName(), attr.Name, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s: invalid provider reference", expr.Range())
+}
+
+var providerMetaArgSchema = &hclext.BodySchema{
+	Attributes: []hclext.AttributeSchema{{Name: "provider"}},
+}
+
+// moduleCtxRunner evaluates expressions in the given module context.
+// This is needed to evaluate expressions retrieved from other modules.
+type moduleCtxRunner struct {
+	tflint.Runner
+
+	moduleCtx tflint.ModuleCtxType
+}
+
+func (r *moduleCtxRunner) EvaluateExpr(expr hcl.Expression, target any, opts *tflint.EvaluateExprOption) error {
+	// Copy the option so as not to modify the caller's one.
+	var option tflint.EvaluateExprOption
+	if opts != nil {
+		option = *opts
+	}
+	option.ModuleCtx = r.moduleCtx
+	return r.Runner.EvaluateExpr(expr, target, &option)
+}
+
+// schemaTarget represents blocks the schema is applied to.
+type schemaTarget struct {
+	// path is the block headers from the top level to the target blocks, e.g. "check" > "data".
+	path []hcl.BlockHeaderSchema
+	// name filters the target blocks by the first label. "" and "*" match all blocks.
+	name string
+}
+
+// expandSchema expands wildcards in the schema with attributes and blocks
+// in the bodies of the target blocks in the given module context.
+func expandSchema(in map[string]any, runner tflint.Runner, moduleCtx tflint.ModuleCtxType, targets ...schemaTarget) (map[string]any, error) {
+	if !hasSchemaWildcard(in) {
+		return in, nil
+	}
+
+	bodies := []hcl.Body{}
+	for _, target := range targets {
+		targetBodies, err := schemaTargetBodies(runner, moduleCtx, target)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, targetBodies...)
+	}
+
+	return expandSchemaWildcard(in, discoverSyntaxSchema(bodies), "schema")
+}
+
+// schemaTargetBodies returns the raw bodies of the target blocks in the given module context.
+// The blocks are looked up via the runner, and then their bodies are taken from the files
+// so that attributes and blocks not in the schema can be discovered.
+func schemaTargetBodies(runner tflint.Runner, moduleCtx tflint.ModuleCtxType, target schemaTarget) ([]hcl.Body, error) {
+	schema := &hclext.BodySchema{}
+	for i := len(target.path) - 1; i >= 0; i-- {
+		schema = &hclext.BodySchema{
+			Blocks: []hclext.BlockSchema{
+				{
+					Type:       target.path[i].Type,
+					LabelNames: target.path[i].LabelNames,
+					Body:       schema,
+				},
+			},
+		}
+	}
+
+	content, err := runner.GetModuleContent(schema, &tflint.GetModuleContentOption{ModuleCtx: moduleCtx, ExpandMode: tflint.ExpandModeNone})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := content.Blocks
+	for range target.path[1:] {
+		inner := hclext.Blocks{}
+		for _, block := range blocks {
+			inner = append(inner, block.Body.Blocks...)
+		}
+		blocks = inner
+	}
+
+	targetRanges := map[string]map[int]bool{}
+	filenames := []string{}
+	for _, block := range blocks {
+		if target.name != "" && target.name != "*" && block.Labels[0] != target.name {
+			continue
+		}
+		filename := block.DefRange.Filename
+		if _, exists := targetRanges[filename]; !exists {
+			targetRanges[filename] = map[int]bool{}
+			filenames = append(filenames, filename)
+		}
+		targetRanges[filename][block.DefRange.Start.Byte] = true
+	}
+	sort.Strings(filenames)
+
+	bodies := []hcl.Body{}
+	for _, filename := range filenames {
+		file, err := runner.GetFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if file == nil {
+			continue
+		}
+
+		fileBodies := []hcl.Body{file.Body}
+		for i, header := range target.path {
+			inner := []hcl.Body{}
+			for _, body := range fileBodies {
+				content, _, diags := body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{header}})
+				if diags.HasErrors() {
+					return nil, diags
+				}
+				for _, block := range content.Blocks {
+					if i == len(target.path)-1 && !targetRanges[filename][block.DefRange.Start.Byte] {
+						continue
+					}
+					inner = append(inner, block.Body)
+				}
+			}
+			fileBodies = inner
+		}
+		bodies = append(bodies, fileBodies...)
+	}
+
+	return bodies, nil
 }
@@ -1,13 +1,19 @@
 package funcs
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
 	"github.com/terraform-linters/tflint-ruleset-opa/opa/tester"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestResourcesFunc(t *testing.T) {
@@ -17,6 +23,7 @@ func TestResourcesFunc(t *testing.T) {
 		resourceType string
 		schema       map[string]any
 		options      map[string]string
+		inputs       map[string]any
 		want         []map[string]any
 	}{
 		{
@@ -72,6 +79,69 @@ resource "aws_s3_bucket" "main" {
 				},
 			},
 		},
+		{
+			name: "root module context",
+			config: `
+resource "aws_instance" "main" {
+	instance_type = "t2.micro"
+}
+
+resource "aws_s3_bucket" "main" {
+	bucket = "foo"
+}`,
+			resourceType: "aws_instance",
+			schema:       map[string]any{"instance_type": "string"},
+			options:      map[string]string{"module_ctx": "root"},
+			inputs: map[string]any{
+				"module_path": "module.child",
+				"root_module": map[string]any{
+					"root.tf": `
+resource "aws_instance" "main" {
+	instance_type = "m5.large"
+}`,
+				},
+			},
+			want: []map[string]any{
+				{
+					"type": "aws_instance",
+					"name": "main",
+					"config": map[string]any{
+						"instance_type": map[string]any{
+							"value":     "m5.large",
+							"unknown":   false,
+							"sensitive": false,
+							"ephemeral": false,
+							"range": map[string]any{
+								"filename": "root.tf",
+								"start": map[string]int{
+									"line":   3,
+									"column": 18,
+									"byte":   51,
+								},
+								"end": map[string]int{
+									"line":   3,
+									"column": 28,
+									"byte":   61,
+								},
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": "root.tf",
+						"start": map[string]int{
+							"line":   2,
+							"column": 1,
+							"byte":   1,
+						},
+						"end": map[string]int{
+							"line":   2,
+							"column": 31,
+							"byte":   31,
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "wildcard",
 			config: `
@@ -243,6 +313,212 @@ resource "aws_instance" "main" {
 				},
 			},
 		},
+		{
+			name: "wildcard schema",
+			config: `
+resource "aws_instance" "main" {
+  ami = "ami-12345678"
+}`,
+			resourceType: "aws_instance",
+			schema:       map[string]any{"*": "string"},
+			want: []map[string]any{
+				{
+					"type": "aws_instance",
+					"name": "main",
+					"config": map[string]any{
+						"ami": map[string]any{
+							"value":     "ami-12345678",
+							"unknown":   false,
+							"sensitive": false,
+							"ephemeral": false,
+							"range": map[string]any{
+								"filename": "main.tf",
+								"start":    map[string]int{"line": 3, "column": 9, "byte": 42},
+								"end":      map[string]int{"line": 3, "column": 23, "byte": 56},
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 1},
+						"end":      map[string]int{"line": 2, "column": 31, "byte": 31},
+					},
+				},
+			},
+		},
+		{
+			name: "wildcard schema discovered per resource type",
+			config: `
+resource "aws_instance" "main" {
+  ami = "ami-12345678"
+}
+
+resource "aws_s3_bucket" "main" {
+  rule {}
+  rule "named" {}
+}`,
+			resourceType: "aws_instance",
+			schema:       map[string]any{"**": "string"},
+			want: []map[string]any{
+				{
+					"type": "aws_instance",
+					"name": "main",
+					"config": map[string]any{
+						"ami": map[string]any{
+							"value":     "ami-12345678",
+							"unknown":   false,
+							"sensitive": false,
+							"ephemeral": false,
+							"range": map[string]any{
+								"filename": "main.tf",
+								"start":    map[string]int{"line": 3, "column": 9, "byte": 42},
+								"end":      map[string]int{"line": 3, "column": 23, "byte": 56},
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 1},
+						"end":      map[string]int{"line": 2, "column": 31, "byte": 31},
+					},
+				},
+			},
+		},
+		{
+			name: "just attributes",
+			config: `
+resource "aws_instance" "main" {
+  labels {
+    env = "prod"
+  }
+}`,
+			resourceType: "aws_instance",
+			schema:       map[string]any{"labels": map[string]any{"__just_attributes": "string"}},
+			want: []map[string]any{
+				{
+					"type": "aws_instance",
+					"name": "main",
+					"config": map[string]any{
+						"labels": []map[string]any{
+							{
+								"config": map[string]any{
+									"env": map[string]any{
+										"value":     "prod",
+										"unknown":   false,
+										"sensitive": false,
+										"ephemeral": false,
+										"range": map[string]any{
+											"filename": "main.tf",
+											"start":    map[string]int{"line": 4, "column": 11, "byte": 55},
+											"end":      map[string]int{"line": 4, "column": 17, "byte": 61},
+										},
+									},
+								},
+								"labels": []string(nil),
+								"decl_range": map[string]any{
+									"filename": "main.tf",
+									"start":    map[string]int{"line": 3, "column": 3, "byte": 36},
+									"end":      map[string]int{"line": 3, "column": 9, "byte": 42},
+								},
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 1},
+						"end":      map[string]int{"line": 2, "column": 31, "byte": 31},
+					},
+				},
+			},
+		},
+		{
+			name: "dynamic blocks",
+			config: `
+resource "aws_security_group" "main" {
+  ingress {
+    from_port = 22
+  }
+
+  dynamic "ingress" {
+    for_each = var.ports
+    iterator = port
+    content {
+      from_port   = 443
+      cidr_blocks = ["0.0.0.0/0"]
+    }
+  }
+}`,
+			resourceType: "aws_security_group",
+			schema:       map[string]any{"ingress": map[string]any{"from_port": "number"}},
+			options:      map[string]string{"expand_mode": "none"},
+			want: []map[string]any{
+				{
+					"type": "aws_security_group",
+					"name": "main",
+					"config": map[string]any{
+						"ingress": []map[string]any{
+							{
+								"config": map[string]any{
+									"from_port": map[string]any{
+										"value":     22,
+										"unknown":   false,
+										"sensitive": false,
+										"ephemeral": false,
+										"range": map[string]any{
+											"filename": "main.tf",
+											"start":    map[string]int{"line": 4, "column": 17, "byte": 68},
+											"end":      map[string]int{"line": 4, "column": 19, "byte": 70},
+										},
+									},
+								},
+								"labels": []string(nil),
+								"decl_range": map[string]any{
+									"filename": "main.tf",
+									"start":    map[string]int{"line": 3, "column": 3, "byte": 42},
+									"end":      map[string]int{"line": 3, "column": 10, "byte": 49},
+								},
+							},
+							{
+								"config": map[string]any{
+									"from_port": map[string]any{
+										"value":     443,
+										"unknown":   false,
+										"sensitive": false,
+										"ephemeral": false,
+										"range": map[string]any{
+											"filename": "main.tf",
+											"start":    map[string]int{"line": 11, "column": 21, "byte": 177},
+											"end":      map[string]int{"line": 11, "column": 24, "byte": 180},
+										},
+									},
+								},
+								"labels": []string(nil),
+								"decl_range": map[string]any{
+									"filename": "main.tf",
+									"start":    map[string]int{"line": 7, "column": 3, "byte": 78},
+									"end":      map[string]int{"line": 7, "column": 20, "byte": 95},
+								},
+								"dynamic": true,
+								"for_each": map[string]any{
+									"value": "var.ports",
+									"range": map[string]any{
+										"filename": "main.tf",
+										"start":    map[string]int{"line": 8, "column": 16, "byte": 113},
+										"end":      map[string]int{"line": 8, "column": 25, "byte": 122},
+									},
+								},
+								"iterator": "port",
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 1},
+						"end":      map[string]int{"line": 2, "column": 37, "byte": 37},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -259,7 +535,11 @@ resource "aws_instance" "main" {
 			if err != nil {
 				t.Fatal(err)
 			}
-			config, err := ast.InterfaceToValue(map[string]string{"main.tf": test.config})
+			sources := map[string]any{"main.tf": test.config}
+			if test.inputs != nil {
+				sources["__inputs__"] = test.inputs
+			}
+			config, err := ast.InterfaceToValue(sources)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -268,7 +548,11 @@ resource "aws_instance" "main" {
 				t.Fatal(err)
 			}
 
-			runner, diags := tester.NewRunner(map[string]string{"main.tf": test.config})
+			inputs, err := jsonToInputs(test.inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runner, diags := tester.NewRunnerWithInputs(map[string]string{"main.tf": test.config}, inputs)
 			if diags.HasErrors() {
 				t.Fatal(diags)
 			}
@@ -1837,3 +2121,2348 @@ func TestModuleRangeFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestFixtureFunc(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join("fixtures", "main.tf"):                `resource "aws_instance" "main" {}`,
+		filepath.Join("fixtures", "main.tf.json"):           `{}`,
+		filepath.Join("fixtures", "terraform.tfvars"):       `instance_type = "t2.micro"`,
+		filepath.Join("fixtures", "README.md"):              "# README",
+		filepath.Join("fixtures", "modules", "vpc", "a.tf"): "",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "relative to policy",
+			dir:  "fixtures",
+			want: map[string]string{
+				"main.tf":          `resource "aws_instance" "main" {}`,
+				"main.tf.json":     `{}`,
+				"terraform.tfvars": `instance_type = "t2.micro"`,
+			},
+		},
+		{
+			name: "missing directory",
+			dir:  "missing",
+			err:  fmt.Sprintf("fixture directory %s does not exist", filepath.Join(dir, "missing")),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := rego.BuiltinContext{Location: &ast.Location{File: filepath.Join(dir, "main_test.rego")}}
+			got, err := FixtureFunc().Impl(ctx, ast.StringTerm(test.dir))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFilesFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []map[string]any
+	}{
+		{
+			name: "files",
+			config: map[string]string{
+				"main.tf":                     "resource \"aws_instance\" \"main\" {\n  instance_type = \"t2.micro\"\n}\n",
+				"main_override.tf":            "locals {}",
+				"variables.tf.json":           `{"variable": {"foo": {}}}`,
+				"override.tf.json":            `{}`,
+				"README.md":                   "# README",
+				filepath.Join("dir", "x.txt"): "",
+			},
+			want: []map[string]any{
+				{
+					"name":     "main.tf",
+					"kind":     "hcl",
+					"override": false,
+					"size":     64,
+					"lines":    3,
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 1, "byte": 0},
+					},
+				},
+				{
+					"name":     "main_override.tf",
+					"kind":     "hcl",
+					"override": true,
+					"size":     9,
+					"lines":    1,
+					"range": map[string]any{
+						"filename": "main_override.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 1, "byte": 0},
+					},
+				},
+				{
+					"name":     "override.tf.json",
+					"kind":     "json",
+					"override": true,
+					"size":     2,
+					"lines":    1,
+					"range": map[string]any{
+						"filename": "override.tf.json",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 1, "byte": 0},
+					},
+				},
+				{
+					"name":     "variables.tf.json",
+					"kind":     "json",
+					"override": false,
+					"size":     25,
+					"lines":    1,
+					"range": map[string]any{
+						"filename": "variables.tf.json",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 1, "byte": 0},
+					},
+				},
+			},
+		},
+		{
+			name:   "no files",
+			config: map[string]string{},
+			want:   []map[string]any{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := FilesFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestReadFileFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		path   string
+		want   *ast.Term
+		err    string
+	}{
+		{
+			name: "file exists",
+			config: map[string]string{
+				"main.tf":   "",
+				"README.md": "# README",
+			},
+			path: "README.md",
+			want: ast.StringTerm("# README"),
+		},
+		{
+			name: "module directory",
+			config: map[string]string{
+				filepath.Join("dir", "main.tf"):   "",
+				filepath.Join("dir", "README.md"): "# README",
+				"README.md":                       "# ROOT",
+			},
+			path: "README.md",
+			want: ast.StringTerm("# README"),
+		},
+		{
+			name:   "file not found",
+			config: map[string]string{"main.tf": ""},
+			path:   "README.md",
+			want:   nil,
+		},
+		{
+			name:   "outside of the module",
+			config: map[string]string{"main.tf": ""},
+			path:   filepath.Join("..", "README.md"),
+			err:    filepath.Join("..", "README.md") + " is not a path within the module directory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := ReadFileFunc(runner).Impl(ctx, ast.StringTerm(test.path))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+			if test.want == nil {
+				if got != nil {
+					t.Fatalf("should be undefined, but got %s", got)
+				}
+				return
+			}
+
+			if diff := cmp.Diff(test.want.String(), got.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestReadFileFunc_symlink(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "module")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range map[string]string{
+		filepath.Join(dir, "main.tf"):   "",
+		filepath.Join(dir, "README.md"): "# README",
+		filepath.Join(root, "secret"):   "secret",
+	} {
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "README.md"), filepath.Join(dir, "DOCS.md")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "secret"), filepath.Join(dir, "secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want *ast.Term
+		err  string
+	}{
+		{
+			name: "symlink within the module",
+			path: "DOCS.md",
+			want: ast.StringTerm("# README"),
+		},
+		{
+			name: "symlink to outside of the module",
+			path: "secret",
+			err:  "secret is not a path within the module directory",
+		},
+	}
+
+	testRunner, diags := tester.NewRunner(map[string]string{"main.tf": ""})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	// The runner does not implement moduleFS, so files are read from the local filesystem.
+	runner := &localFSRunner{Runner: testRunner, dir: dir}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := rego.BuiltinContext{}
+			got, err := ReadFileFunc(runner).Impl(ctx, ast.StringTerm(test.path))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+			if diff := cmp.Diff(test.want.String(), got.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type localFSRunner struct {
+	tflint.Runner
+
+	dir string
+}
+
+// GetFiles returns files as if the module is located in the directory.
+func (r *localFSRunner) GetFiles() (map[string]*hcl.File, error) {
+	files, err := r.Runner.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]*hcl.File{}
+	for name, file := range files {
+		ret[filepath.Join(r.dir, name)] = file
+	}
+	return ret, nil
+}
+
+func TestCommentsFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []map[string]any
+	}{
+		{
+			name: "comments",
+			config: map[string]string{
+				"main.tf": `# owner: team-a
+# second line
+module "vpc" {
+  source = "./vpc" // TODO: pin
+}
+
+/* detached */
+
+locals {}`,
+				"main.tf.json": `{"locals": {}}`,
+			},
+			want: []map[string]any{
+				{
+					"text":  "# owner: team-a",
+					"style": "#",
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 16, "byte": 15},
+					},
+					"precedes": map[string]any{
+						"kind":   "block",
+						"name":   "module",
+						"labels": []string{"vpc"},
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 3, "column": 1, "byte": 30},
+							"end":      map[string]int{"line": 3, "column": 13, "byte": 42},
+						},
+					},
+				},
+				{
+					"text":  "# second line",
+					"style": "#",
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 16},
+						"end":      map[string]int{"line": 2, "column": 14, "byte": 29},
+					},
+					"precedes": map[string]any{
+						"kind":   "block",
+						"name":   "module",
+						"labels": []string{"vpc"},
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 3, "column": 1, "byte": 30},
+							"end":      map[string]int{"line": 3, "column": 13, "byte": 42},
+						},
+					},
+				},
+				{
+					"text":  "// TODO: pin",
+					"style": "//",
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 4, "column": 20, "byte": 64},
+						"end":      map[string]int{"line": 4, "column": 32, "byte": 76},
+					},
+					"trails": map[string]any{
+						"kind":   "attribute",
+						"name":   "source",
+						"labels": []string{},
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 4, "column": 3, "byte": 47},
+							"end":      map[string]int{"line": 4, "column": 19, "byte": 63},
+						},
+					},
+				},
+				{
+					"text":  "/* detached */",
+					"style": "/* */",
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 7, "column": 1, "byte": 80},
+						"end":      map[string]int{"line": 7, "column": 15, "byte": 94},
+					},
+				},
+			},
+		},
+		{
+			name: "trailing block comment",
+			config: map[string]string{
+				"main.tf": `locals { /* inline */ }`,
+			},
+			want: []map[string]any{
+				{
+					"text":  "/* inline */",
+					"style": "/* */",
+					"range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 10, "byte": 9},
+						"end":      map[string]int{"line": 1, "column": 22, "byte": 21},
+					},
+				},
+			},
+		},
+		{
+			name:   "no files",
+			config: map[string]string{},
+			want:   []map[string]any{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := CommentsFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestUnformattedFilesFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []map[string]any
+	}{
+		{
+			name: "unformatted",
+			config: map[string]string{
+				"main.tf":      "resource \"aws_instance\" \"main\" {\ninstance_type=\"t2.micro\"\n  ami = \"ami-123456\"\n}\n\nlocals {\n  foo = 1\n    bar = 2\n}\n",
+				"variables.tf": "variable \"foo\" {}\n",
+				"main.tf.json": `{"locals":   {}}`,
+			},
+			want: []map[string]any{
+				{
+					"filename": "main.tf",
+					"diffs": []map[string]any{
+						{
+							"range": map[string]any{
+								"filename": "main.tf",
+								"start":    map[string]int{"line": 2, "column": 1, "byte": 33},
+								"end":      map[string]int{"line": 3, "column": 21, "byte": 78},
+							},
+							"formatted": "  instance_type = \"t2.micro\"\n  ami           = \"ami-123456\"",
+						},
+						{
+							"range": map[string]any{
+								"filename": "main.tf",
+								"start":    map[string]int{"line": 8, "column": 1, "byte": 101},
+								"end":      map[string]int{"line": 8, "column": 12, "byte": 112},
+							},
+							"formatted": "  bar = 2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "formatted",
+			config: map[string]string{
+				"main.tf": "locals {\n  foo = 1\n}\n",
+			},
+			want: []map[string]any{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := UnformattedFilesFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLockfileFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []map[string]any
+		err    string
+	}{
+		{
+			name: "lock file",
+			config: map[string]string{
+				"main.tf": "",
+				".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.0.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+  ]
+}
+`,
+			},
+			want: []map[string]any{
+				{
+					"source": "registry.terraform.io/hashicorp/aws",
+					"version": map[string]any{
+						"value": "5.0.0",
+						"range": map[string]any{
+							"filename": ".terraform.lock.hcl",
+							"start":    map[string]int{"line": 2, "column": 17, "byte": 65},
+							"end":      map[string]int{"line": 2, "column": 24, "byte": 72},
+						},
+					},
+					"constraints": map[string]any{
+						"value": "~> 5.0",
+						"range": map[string]any{
+							"filename": ".terraform.lock.hcl",
+							"start":    map[string]int{"line": 3, "column": 17, "byte": 89},
+							"end":      map[string]int{"line": 3, "column": 25, "byte": 97},
+						},
+					},
+					"hashes": []map[string]any{
+						{
+							"value": "h1:abc=",
+							"range": map[string]any{
+								"filename": ".terraform.lock.hcl",
+								"start":    map[string]int{"line": 5, "column": 5, "byte": 115},
+								"end":      map[string]int{"line": 5, "column": 14, "byte": 124},
+							},
+						},
+					},
+					"decl_range": map[string]any{
+						"filename": ".terraform.lock.hcl",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 47, "byte": 46},
+					},
+				},
+			},
+		},
+		{
+			name: "no lock file",
+			config: map[string]string{
+				"main.tf": "",
+			},
+			want: []map[string]any{},
+		},
+		{
+			name: "invalid lock file",
+			config: map[string]string{
+				"main.tf":             "",
+				".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {}`,
+			},
+			err: `.terraform.lock.hcl:1,48-48: Missing required argument; The argument "version" is required, but no definition was found.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := LockfileFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTfvarsFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		want   []map[string]any
+		err    string
+	}{
+		{
+			name: "tfvars",
+			config: map[string]string{
+				"main.tf":                           "",
+				"prod.tfvars":                       "region = \"us-east-1\"\ntags   = { env = \"prod\" }\n",
+				"dev.auto.tfvars.json":              `{"count": 1}`,
+				filepath.Join("envs", "stg.tfvars"): `region = "us-west-2"`,
+			},
+			want: []map[string]any{
+				{
+					"name":     "count",
+					"filename": "dev.auto.tfvars.json",
+					"expr": map[string]any{
+						"value": "1",
+						"range": map[string]any{
+							"filename": "dev.auto.tfvars.json",
+							"start":    map[string]int{"line": 1, "column": 11, "byte": 10},
+							"end":      map[string]int{"line": 1, "column": 12, "byte": 11},
+						},
+					},
+					"value": 1,
+					"decl_range": map[string]any{
+						"filename": "dev.auto.tfvars.json",
+						"start":    map[string]int{"line": 1, "column": 2, "byte": 1},
+						"end":      map[string]int{"line": 1, "column": 12, "byte": 11},
+					},
+				},
+				{
+					"name":     "region",
+					"filename": "prod.tfvars",
+					"expr": map[string]any{
+						"value": `"us-east-1"`,
+						"range": map[string]any{
+							"filename": "prod.tfvars",
+							"start":    map[string]int{"line": 1, "column": 10, "byte": 9},
+							"end":      map[string]int{"line": 1, "column": 21, "byte": 20},
+						},
+					},
+					"value": "us-east-1",
+					"decl_range": map[string]any{
+						"filename": "prod.tfvars",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 21, "byte": 20},
+					},
+				},
+				{
+					"name":     "tags",
+					"filename": "prod.tfvars",
+					"expr": map[string]any{
+						"value": `{ env = "prod" }`,
+						"range": map[string]any{
+							"filename": "prod.tfvars",
+							"start":    map[string]int{"line": 2, "column": 10, "byte": 30},
+							"end":      map[string]int{"line": 2, "column": 26, "byte": 46},
+						},
+					},
+					"value": map[string]any{"env": "prod"},
+					"decl_range": map[string]any{
+						"filename": "prod.tfvars",
+						"start":    map[string]int{"line": 2, "column": 1, "byte": 21},
+						"end":      map[string]int{"line": 2, "column": 26, "byte": 46},
+					},
+				},
+			},
+		},
+		{
+			name: "no tfvars",
+			config: map[string]string{
+				"main.tf": "",
+			},
+			want: []map[string]any{},
+		},
+		{
+			name: "references",
+			config: map[string]string{
+				"main.tf":     "",
+				"prod.tfvars": `region = var.default_region`,
+			},
+			err: `prod.tfvars:1,10-13: Variables not allowed; Variables may not be used here.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := TfvarsFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTestsFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		schema map[string]any
+		want   []map[string]any
+		err    string
+	}{
+		{
+			name: "tests",
+			config: map[string]string{
+				"main.tf": "",
+				filepath.Join("tests", "main.tftest.hcl"): `
+variables {
+  region = "us-east-1"
+}
+
+run "plan" {
+  command = plan
+
+  assert {
+    condition     = aws_instance.main.tags["Name"] == "main"
+    error_message = "invalid tags"
+  }
+}`,
+				"README.md": "",
+			},
+			schema: map[string]any{
+				"variables": map[string]any{"region": "string"},
+				"run": map[string]any{
+					"command": "expr",
+					"assert":  map[string]any{"condition": "bool", "error_message": "string"},
+				},
+			},
+			want: []map[string]any{
+				{
+					"filename": filepath.Join("tests", "main.tftest.hcl"),
+					"config": map[string]any{
+						"variables": []map[string]any{
+							{
+								"config": map[string]any{
+									"region": map[string]any{
+										"value":     "us-east-1",
+										"unknown":   false,
+										"sensitive": false,
+										"ephemeral": false,
+										"range": map[string]any{
+											"filename": filepath.Join("tests", "main.tftest.hcl"),
+											"start":    map[string]int{"line": 3, "column": 12, "byte": 24},
+											"end":      map[string]int{"line": 3, "column": 23, "byte": 35},
+										},
+									},
+								},
+								"labels": []string(nil),
+								"decl_range": map[string]any{
+									"filename": filepath.Join("tests", "main.tftest.hcl"),
+									"start":    map[string]int{"line": 2, "column": 1, "byte": 1},
+									"end":      map[string]int{"line": 2, "column": 10, "byte": 10},
+								},
+							},
+						},
+						"run": []map[string]any{
+							{
+								"config": map[string]any{
+									"command": map[string]any{
+										"value": "plan",
+										"range": map[string]any{
+											"filename": filepath.Join("tests", "main.tftest.hcl"),
+											"start":    map[string]int{"line": 7, "column": 13, "byte": 64},
+											"end":      map[string]int{"line": 7, "column": 17, "byte": 68},
+										},
+									},
+									"assert": []map[string]any{
+										{
+											"config": map[string]any{
+												"condition": map[string]any{
+													"unknown":   true,
+													"sensitive": false,
+													"ephemeral": false,
+													"range": map[string]any{
+														"filename": filepath.Join("tests", "main.tftest.hcl"),
+														"start":    map[string]int{"line": 10, "column": 21, "byte": 101},
+														"end":      map[string]int{"line": 10, "column": 61, "byte": 141},
+													},
+												},
+												"error_message": map[string]any{
+													"value":     "invalid tags",
+													"unknown":   false,
+													"sensitive": false,
+													"ephemeral": false,
+													"range": map[string]any{
+														"filename": filepath.Join("tests", "main.tftest.hcl"),
+														"start":    map[string]int{"line": 11, "column": 21, "byte": 162},
+														"end":      map[string]int{"line": 11, "column": 35, "byte": 176},
+													},
+												},
+											},
+											"labels": []string(nil),
+											"decl_range": map[string]any{
+												"filename": filepath.Join("tests", "main.tftest.hcl"),
+												"start":    map[string]int{"line": 9, "column": 3, "byte": 72},
+												"end":      map[string]int{"line": 9, "column": 9, "byte": 78},
+											},
+										},
+									},
+								},
+								"labels": []string{"plan"},
+								"decl_range": map[string]any{
+									"filename": filepath.Join("tests", "main.tftest.hcl"),
+									"start":    map[string]int{"line": 6, "column": 1, "byte": 39},
+									"end":      map[string]int{"line": 6, "column": 11, "byte": 49},
+								},
+							},
+						},
+					},
+					"range": map[string]any{
+						"filename": filepath.Join("tests", "main.tftest.hcl"),
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 1, "byte": 0},
+					},
+				},
+			},
+		},
+		{
+			name: "no tests",
+			config: map[string]string{
+				"main.tf": "",
+			},
+			schema: map[string]any{"run": map[string]any{}},
+			want:   []map[string]any{},
+		},
+		{
+			name: "invalid schema",
+			config: map[string]string{
+				"main.tf": "",
+			},
+			schema: map[string]any{"run": map[string]any{"command": "invalid"}},
+			err:    `type constraint parse error in schema.run.command; Invalid type specification; The keyword "invalid" is not a valid type specification.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+			schema, err := ast.InterfaceToValue(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := TestsFunc(runner).Impl(ctx, ast.NewTerm(schema))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestModuleManifestFunc(t *testing.T) {
+	manifest := `{"Modules":[
+  {"Key":"","Source":"","Dir":"."},
+  {"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.0.0","Dir":".terraform/modules/vpc"},
+  {"Key":"vpc.subnets","Source":"./modules/subnets","Dir":".terraform/modules/vpc/modules/subnets"},
+  {"Key":"app","Source":"git::https://example.com/app.git?ref=main","Dir":".terraform/modules/app"}
+]}`
+	config := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.0"
+}
+
+module "app" {
+  source = "git::https://example.com/app.git?ref=main"
+}
+
+module "uninstalled" {
+  source = "./uninstalled"
+}`
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		dataDir string
+		want    []map[string]any
+		err     string
+	}{
+		{
+			name: "manifest",
+			config: map[string]string{
+				"main.tf": config,
+				filepath.Join(".terraform", "modules", "modules.json"): manifest,
+			},
+			want: []map[string]any{
+				{
+					"key":       "vpc",
+					"name":      "vpc",
+					"installed": true,
+					"source":    "registry.terraform.io/terraform-aws-modules/vpc/aws",
+					"version":   "5.0.0",
+					"dir":       ".terraform/modules/vpc",
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 13, "byte": 12},
+					},
+				},
+				{
+					"key":       "app",
+					"name":      "app",
+					"installed": true,
+					"source":    "git::https://example.com/app.git?ref=main",
+					"dir":       ".terraform/modules/app",
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 6, "column": 1, "byte": 83},
+						"end":      map[string]int{"line": 6, "column": 13, "byte": 95},
+					},
+				},
+				{
+					"key":       "uninstalled",
+					"name":      "uninstalled",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 10, "column": 1, "byte": 156},
+						"end":      map[string]int{"line": 10, "column": 21, "byte": 176},
+					},
+				},
+			},
+		},
+		{
+			name: "TF_DATA_DIR",
+			config: map[string]string{
+				"main.tf": config,
+				filepath.Join("data", "modules", "modules.json"): `{"Modules":[{"Key":"app","Source":"./app","Dir":"app"}]}`,
+			},
+			dataDir: "data",
+			want: []map[string]any{
+				{
+					"key":       "vpc",
+					"name":      "vpc",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 13, "byte": 12},
+					},
+				},
+				{
+					"key":       "app",
+					"name":      "app",
+					"installed": true,
+					"source":    "./app",
+					"dir":       "app",
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 6, "column": 1, "byte": 83},
+						"end":      map[string]int{"line": 6, "column": 13, "byte": 95},
+					},
+				},
+				{
+					"key":       "uninstalled",
+					"name":      "uninstalled",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 10, "column": 1, "byte": 156},
+						"end":      map[string]int{"line": 10, "column": 21, "byte": 176},
+					},
+				},
+			},
+		},
+		{
+			name: "not installed",
+			config: map[string]string{
+				"main.tf": config,
+			},
+			want: []map[string]any{
+				{
+					"key":       "vpc",
+					"name":      "vpc",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+						"end":      map[string]int{"line": 1, "column": 13, "byte": 12},
+					},
+				},
+				{
+					"key":       "app",
+					"name":      "app",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 6, "column": 1, "byte": 83},
+						"end":      map[string]int{"line": 6, "column": 13, "byte": 95},
+					},
+				},
+				{
+					"key":       "uninstalled",
+					"name":      "uninstalled",
+					"installed": false,
+					"decl_range": map[string]any{
+						"filename": "main.tf",
+						"start":    map[string]int{"line": 10, "column": 1, "byte": 156},
+						"end":      map[string]int{"line": 10, "column": 21, "byte": 176},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid manifest",
+			config: map[string]string{
+				"main.tf": config,
+				filepath.Join(".terraform", "modules", "modules.json"): `{`,
+			},
+			err: fmt.Sprintf("failed to parse %s; unexpected end of JSON input", filepath.Join(".terraform", "modules", "modules.json")),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TF_DATA_DIR", test.dataDir)
+
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := ModuleManifestFunc(runner).Impl(ctx, []*ast.Term{})
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintParseFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint any
+		want       []map[string]string
+		undefined  bool
+		err        string
+	}{
+		{
+			name:       "string",
+			constraint: "~> 5.0, != 5.3.1",
+			want: []map[string]string{
+				{"operator": "~>", "version": "5.0"},
+				{"operator": "!=", "version": "5.3.1"},
+			},
+		},
+		{
+			name:       "no operator",
+			constraint: "1.5.0",
+			want: []map[string]string{
+				{"operator": "=", "version": "1.5.0"},
+			},
+		},
+		{
+			name: "expr",
+			constraint: map[string]any{
+				"value":   ">= 1.5",
+				"unknown": false,
+				"range":   map[string]any{},
+			},
+			want: []map[string]string{
+				{"operator": ">=", "version": "1.5"},
+			},
+		},
+		{
+			name: "unknown expr",
+			constraint: map[string]any{
+				"unknown": true,
+				"range":   map[string]any{},
+			},
+			undefined: true,
+		},
+		{
+			name:       "malformed",
+			constraint: "~> foo",
+			err:        "malformed constraint: ~> foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			constraint, err := ast.InterfaceToValue(test.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := VersionConstraintParseFunc().Impl(ctx, ast.NewTerm(constraint))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+			if test.undefined {
+				if got != nil {
+					t.Fatalf("should be undefined, but got %s", got)
+				}
+				return
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintAllowsFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint any
+		version    any
+		want       bool
+		err        string
+	}{
+		{
+			name:       "allowed",
+			constraint: "~> 5.0, != 5.3.1",
+			version:    "5.40.0",
+			want:       true,
+		},
+		{
+			name:       "excluded",
+			constraint: "~> 5.0, != 5.3.1",
+			version:    "5.3.1",
+			want:       false,
+		},
+		{
+			name:       "major version",
+			constraint: "~> 5.0",
+			version:    "4.67.0",
+			want:       false,
+		},
+		{
+			name:       "expr",
+			constraint: map[string]any{"value": ">= 1.5", "unknown": false, "range": map[string]any{}},
+			version:    "1.9.0",
+			want:       true,
+		},
+		{
+			name:       "raw expr",
+			constraint: map[string]any{"value": `">= 1.5"`, "range": map[string]any{}},
+			version:    "1.9.0",
+			want:       true,
+		},
+		{
+			name:       "raw expr with references",
+			constraint: map[string]any{"value": `">= ${var.version}"`, "range": map[string]any{}},
+			version:    "1.9.0",
+			err:        `version must be a literal string, got ">= ${var.version}"`,
+		},
+		{
+			name:       "invalid version",
+			constraint: "~> 5.0",
+			version:    "foo",
+			err:        "malformed version: foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			constraint, err := ast.InterfaceToValue(test.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			version, err := ast.InterfaceToValue(test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := VersionConstraintAllowsFunc().Impl(ctx, ast.NewTerm(constraint), ast.NewTerm(version))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			if diff := cmp.Diff(ast.Boolean(test.want).String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintAllowsFunc_settings(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		ty     string
+		want   bool
+	}{
+		{
+			name:   "expr",
+			config: map[string]string{"main.tf": `terraform { required_version = ">= 1.5" }`},
+			ty:     "expr",
+			want:   true,
+		},
+		{
+			name:   "string",
+			config: map[string]string{"main.tf": `terraform { required_version = ">= 1.5" }`},
+			ty:     "string",
+			want:   true,
+		},
+		{
+			name:   "heredoc",
+			config: map[string]string{"main.tf": "terraform {\n  required_version = <<EOF\n< 1.5\nEOF\n}"},
+			ty:     "expr",
+			want:   false,
+		},
+		{
+			name:   "JSON",
+			config: map[string]string{"main.tf.json": `{"terraform": {"required_version": ">= 1.5"}}`},
+			ty:     "expr",
+			want:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			schema, err := ast.InterfaceToValue(map[string]any{"required_version": test.ty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			options, err := ast.InterfaceToValue(map[string]string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			settings, err := SettingsFunc(runner).Impl(ctx, ast.NewTerm(schema), ast.NewTerm(options))
+			if err != nil {
+				t.Fatal(err)
+			}
+			constraint := settings.Value.(*ast.Array).Elem(0).Get(ast.StringTerm("config")).Get(ast.StringTerm("required_version"))
+
+			got, err := VersionConstraintAllowsFunc().Impl(ctx, constraint, ast.StringTerm("1.9.0"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(ast.Boolean(test.want).String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintPessimisticFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint string
+		want       bool
+	}{
+		{
+			name:       "pessimistic",
+			constraint: ">= 5.0, ~> 5.1",
+			want:       true,
+		},
+		{
+			name:       "not pessimistic",
+			constraint: ">= 5.0, < 6.0",
+			want:       false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := rego.BuiltinContext{}
+			got, err := VersionConstraintPessimisticFunc().Impl(ctx, ast.StringTerm(test.constraint))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(ast.Boolean(test.want).String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTypeConstraintFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want map[string]any
+		err  string
+	}{
+		{
+			name: "primitive",
+			expr: "string",
+			want: map[string]any{"type": "string"},
+		},
+		{
+			name: "any",
+			expr: "any",
+			want: map[string]any{"type": "any"},
+		},
+		{
+			name: "collection",
+			expr: "list(map(number))",
+			want: map[string]any{
+				"type": "list",
+				"element": map[string]any{
+					"type":    "map",
+					"element": map[string]any{"type": "number"},
+				},
+			},
+		},
+		{
+			name: "object with optional attributes",
+			expr: `object({ name = string, tags = optional(map(string), {}), rules = optional(list(object({ port = optional(number, 443) }))) })`,
+			want: map[string]any{
+				"type": "object",
+				"attributes": map[string]any{
+					"name": map[string]any{"type": "string", "optional": false},
+					"tags": map[string]any{
+						"type":     "map",
+						"element":  map[string]any{"type": "string"},
+						"optional": true,
+						"default":  map[string]any{},
+					},
+					"rules": map[string]any{
+						"type": "list",
+						"element": map[string]any{
+							"type": "object",
+							"attributes": map[string]any{
+								"port": map[string]any{"type": "number", "optional": true, "default": 443},
+							},
+						},
+						"optional": true,
+					},
+				},
+			},
+		},
+		{
+			name: "tuple",
+			expr: "tuple([string, bool])",
+			want: map[string]any{
+				"type": "tuple",
+				"elements": []map[string]any{
+					{"type": "string"},
+					{"type": "bool"},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			expr: "foo",
+			err:  `variables.tf:1,1-4: Invalid type specification; The keyword "foo" is not a valid type specification.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := ast.InterfaceToValue(map[string]any{
+				"value": test.expr,
+				"range": map[string]any{
+					"filename": "variables.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": len(test.expr) + 1, "byte": len(test.expr)},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := TypeConstraintFunc().Impl(ctx, ast.NewTerm(expr))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestResourceProviderFunc(t *testing.T) {
+	config := `provider "aws" {
+  region = "us-east-1"
+}
+
+provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+}
+
+resource "aws_instance" "main" {}
+
+resource "aws_instance" "west" {
+  provider = aws.west
+}
+
+data "google_project" "main" {}
+
+resource "aws_instance" "east" {
+  provider = aws.east
+}
+
+module "child" {
+  source = "./child"
+
+  providers = {
+    aws.east = aws.west
+  }
+}`
+
+	resourceIn := func(filename string, line int, byte int) map[string]any {
+		return map[string]any{
+			"decl_range": map[string]any{
+				"filename": filename,
+				"start":    map[string]int{"line": line, "column": 1, "byte": byte},
+				"end":      map[string]int{"line": line, "column": 1, "byte": byte},
+			},
+		}
+	}
+	resource := func(line int, byte int) map[string]any {
+		return resourceIn("main.tf", line, byte)
+	}
+	schema := map[string]any{"region": "string"}
+	manifest := `{"Modules": [{"Key": "child", "Source": "./modules/child", "Dir": "modules/child"}]}`
+
+	tests := []struct {
+		name       string
+		resource   map[string]any
+		modulePath string
+		files      map[string]string
+		want       map[string]any
+	}{
+		{
+			name:     "default provider",
+			resource: resource(10, 104),
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "us-east-1",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 2, "column": 12, "byte": 28},
+							"end":      map[string]int{"line": 2, "column": 23, "byte": 39},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 15, "byte": 14},
+				},
+			},
+		},
+		{
+			name:     "aliased provider",
+			resource: resource(12, 139),
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"alias":  "west",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "us-west-2",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 7, "column": 12, "byte": 89},
+							"end":      map[string]int{"line": 7, "column": 23, "byte": 100},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 5, "column": 1, "byte": 43},
+					"end":      map[string]int{"line": 5, "column": 15, "byte": 57},
+				},
+			},
+		},
+		{
+			name:     "provider not configured",
+			resource: resource(16, 197),
+			want:     map[string]any{"name": "google", "source": "registry.terraform.io/hashicorp/google"},
+		},
+		{
+			name:     "provider source in required_providers",
+			resource: resource(16, 197),
+			files: map[string]string{
+				"versions.tf": `terraform {
+  required_providers {
+    aws = "~> 5.0"
+    google = {
+      source                = "hashicorp/google-beta"
+      configuration_aliases = [google.west]
+    }
+  }
+}`,
+			},
+			want: map[string]any{"name": "google", "source": "registry.terraform.io/hashicorp/google-beta"},
+		},
+		{
+			name:       "inherited provider",
+			resource:   resource(18, 230),
+			modulePath: "module.child",
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"alias":  "east",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "us-west-2",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 7, "column": 12, "byte": 89},
+							"end":      map[string]int{"line": 7, "column": 23, "byte": 100},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 5, "column": 1, "byte": 43},
+					"end":      map[string]int{"line": 5, "column": 15, "byte": 57},
+				},
+			},
+		},
+		{
+			name:       "provider not passed",
+			resource:   resource(18, 230),
+			modulePath: "module.other",
+			want:       map[string]any{"name": "aws", "alias": "east", "source": "registry.terraform.io/hashicorp/aws"},
+		},
+		{
+			name:       "provider inherited through nested modules",
+			resource:   resource(18, 230),
+			modulePath: "module.child.module.grandchild",
+			files: map[string]string{
+				".terraform/modules/modules.json": manifest,
+				"modules/child/main.tf": `module "grandchild" {
+  providers = {
+    aws.east = aws.east
+  }
+}`,
+			},
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"alias":  "east",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "us-west-2",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 7, "column": 12, "byte": 89},
+							"end":      map[string]int{"line": 7, "column": 23, "byte": 100},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 5, "column": 1, "byte": 43},
+					"end":      map[string]int{"line": 5, "column": 15, "byte": 57},
+				},
+			},
+		},
+		{
+			name:       "provider configured in intermediate module",
+			resource:   resource(18, 230),
+			modulePath: "module.child.module.grandchild",
+			files: map[string]string{
+				".terraform/modules/modules.json": manifest,
+				"modules/child/main.tf": `provider "aws" {
+  alias  = "central"
+  region = "eu-central-1"
+}
+
+module "grandchild" {
+  providers = {
+    aws.east = aws.central
+  }
+}`,
+			},
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"alias":  "east",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "eu-central-1",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "modules/child/main.tf",
+							"start":    map[string]int{"line": 3, "column": 12, "byte": 49},
+							"end":      map[string]int{"line": 3, "column": 26, "byte": 63},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "modules/child/main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 15, "byte": 14},
+				},
+			},
+		},
+		{
+			name:       "provider not passed through nested modules",
+			resource:   resource(18, 230),
+			modulePath: "module.child.module.grandchild",
+			files: map[string]string{
+				".terraform/modules/modules.json": manifest,
+				"modules/child/main.tf":           `module "grandchild" {}`,
+			},
+			want: map[string]any{"name": "aws", "alias": "east", "source": "registry.terraform.io/hashicorp/aws"},
+		},
+		{
+			name:       "intermediate module not installed",
+			resource:   resource(18, 230),
+			modulePath: "module.child.module.grandchild",
+			want:       map[string]any{"name": "aws", "alias": "east", "source": "registry.terraform.io/hashicorp/aws", "unknown": true},
+		},
+		{
+			name:       "resource in root module",
+			resource:   resourceIn("root.tf", 1, 0),
+			modulePath: "module.child",
+			want: map[string]any{
+				"name":   "aws",
+				"source": "registry.terraform.io/hashicorp/aws",
+				"config": map[string]any{
+					"region": map[string]any{
+						"value":     "us-east-1",
+						"unknown":   false,
+						"sensitive": false,
+						"ephemeral": false,
+						"range": map[string]any{
+							"filename": "main.tf",
+							"start":    map[string]int{"line": 2, "column": 12, "byte": 28},
+							"end":      map[string]int{"line": 2, "column": 23, "byte": 39},
+						},
+					},
+				},
+				"decl_range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 15, "byte": 14},
+				},
+			},
+		},
+		{
+			name:     "resource not found",
+			resource: resource(1, 0),
+			want:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string]string{"main.tf": config}
+			for name, src := range test.files {
+				files[name] = src
+			}
+			var inputs tester.Inputs
+			if test.modulePath != "" {
+				// The root module has the same config as the current module, except for "root.tf".
+				inputs.ModulePath = test.modulePath
+				inputs.RootModule = map[string]string{"main.tf": config, "root.tf": `resource "aws_instance" "root" {}`}
+			}
+			runner, diags := tester.NewRunnerWithInputs(files, inputs)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			resourceTerm, err := ast.InterfaceToValue(test.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schemaTerm, err := ast.InterfaceToValue(schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := ResourceProviderFunc(runner).Impl(ctx, ast.NewTerm(resourceTerm), ast.NewTerm(schemaTerm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == nil {
+				if got != nil {
+					t.Fatalf("should be undefined, but got %s", got)
+				}
+				return
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestModuleCtxRunner_EvaluateExpr(t *testing.T) {
+	runner, diags := tester.NewRunnerWithInputs(map[string]string{"main.tf": `locals { region = "us-west-2" }`}, tester.Inputs{
+		ModulePath: "module.child",
+		RootModule: map[string]string{"main.tf": `locals { region = "us-east-1" }`},
+	})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(`local.region`), "main.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	opts := &tflint.EvaluateExprOption{WantType: &cty.String}
+	var got string
+	if err := (&moduleCtxRunner{Runner: runner, moduleCtx: tflint.RootModuleCtxType}).EvaluateExpr(expr, &got, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got != "us-east-1" {
+		t.Errorf("want: us-east-1, got: %s", got)
+	}
+	if opts.ModuleCtx != tflint.SelfModuleCtxType {
+		t.Errorf("the passed option should not be modified, but the module context is %s", opts.ModuleCtx)
+	}
+}
+
+func TestEvalFunc(t *testing.T) {
+	config := `
+variable "env" {
+  default = "prod"
+}
+
+variable "password" {
+  sensitive = true
+}
+
+variable "region" {
+  default = "us-east-1"
+}
+
+variable "zone" {
+  default = "us-east-1a"
+}
+
+variable "static" {
+  default = "static"
+}
+
+variable "unused" {
+  default = "unused"
+}
+
+variable "settings" {
+  default = { foo = "bar" }
+}
+
+locals {
+  tags       = { Env = var.env }
+  name       = upper(var.env)
+  cidr       = "10.0.0.0/16"
+  upper_tags = { for k, v in local.tags : k => upper(v) }
+  regions    = [for var in [{ region = "ap-northeast-1" }] : var.region]
+}
+
+resource "aws_instance" "main" {
+  tags     = merge(local.tags, { Name = local.name })
+  password = var.password
+
+  dynamic "setting" {
+    for_each = var.settings
+    iterator = var
+    content {
+      value = var.zone
+    }
+  }
+
+  depends_on = [var.static]
+}`
+
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+		err  string
+	}{
+		{
+			name: "references",
+			src:  `{ tags = local.tags, env = var.env }`,
+			want: map[string]any{
+				"value":     map[string]any{"tags": map[string]any{"Env": "prod"}, "env": "prod"},
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 37, "byte": 36},
+				},
+			},
+		},
+		{
+			name: "template",
+			src:  `"${var.env}-app"`,
+			want: map[string]any{
+				"value":     "prod-app",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 17, "byte": 16},
+				},
+			},
+		},
+		{
+			name: "function call",
+			src:  `lower(var.env)`,
+			want: map[string]any{
+				"value":     "prod",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 15, "byte": 14},
+				},
+			},
+		},
+		{
+			name: "function call with local value",
+			src:  `cidrsubnet(local.cidr, 8, 1)`,
+			want: map[string]any{
+				"value":     "10.0.1.0/24",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 29, "byte": 28},
+				},
+			},
+		},
+		{
+			name: "nested function calls",
+			src:  `merge(local.tags, { Name = upper(local.name) })`,
+			want: map[string]any{
+				"value":     map[string]any{"Env": "prod", "Name": "PROD"},
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 48, "byte": 47},
+				},
+			},
+		},
+		{
+			name: "filesystem function",
+			src:  `file("foo.txt")`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 16, "byte": 15},
+				},
+			},
+		},
+		{
+			name: "path",
+			src:  `"${path.module}/files"`,
+			want: map[string]any{
+				"value":     "./files",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 23, "byte": 22},
+				},
+			},
+		},
+		{
+			name: "workspace",
+			src:  `terraform.workspace`,
+			want: map[string]any{
+				"value":     "default",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 20, "byte": 19},
+				},
+			},
+		},
+		{
+			name: "resource reference",
+			src:  `aws_instance.main.id`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 21, "byte": 20},
+				},
+			},
+		},
+		{
+			name: "unreferenced variable",
+			src:  `var.unused`,
+			err:  `:1,1-11: var.unused cannot be evaluated because it is not referenced in the module`,
+		},
+		{
+			name: "undeclared local value",
+			src:  `local.undeclared`,
+			err:  `:1,1-17: local value "undeclared" is not declared`,
+		},
+		{
+			name: "sensitive",
+			src:  `var.password`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": true,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 13, "byte": 12},
+				},
+			},
+		},
+		{
+			name: "single name reference",
+			src:  `foo`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 4, "byte": 3},
+				},
+			},
+		},
+		{
+			name: "index reference",
+			src:  `var["env"]`,
+			want: map[string]any{
+				"value":     "prod",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 11, "byte": 10},
+				},
+			},
+		},
+		{
+			name: "root only reference",
+			src:  `path`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 5, "byte": 4},
+				},
+			},
+		},
+		{
+			name: "for expression variable",
+			src:  `upper(v)`,
+			want: map[string]any{
+				"unknown":   true,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+					"end":      map[string]int{"line": 1, "column": 9, "byte": 8},
+				},
+			},
+		},
+		{
+			name: "reference shadowed by for expression variable",
+			src:  `var.region`,
+			err:  `:1,1-11: var.region cannot be evaluated because it is not referenced in the module`,
+		},
+		{
+			name: "reference shadowed by dynamic block iterator",
+			src:  `var.zone`,
+			err:  `:1,1-9: var.zone cannot be evaluated because it is not referenced in the module`,
+		},
+		{
+			name: "static attribute",
+			src:  `var.static`,
+			err:  `:1,1-11: var.static cannot be evaluated because it is not referenced in the module`,
+		},
+		{
+			name: "invalid expression",
+			src:  `var.`,
+			err:  `:1,5-5: Invalid attribute name; An attribute name is required after a dot.`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(map[string]string{"main.tf": config})
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := EvalFunc(runner).Impl(ctx, ast.StringTerm(test.src))
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestEvalAtFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]string
+		src    string
+		rng    map[string]any
+		want   map[string]any
+	}{
+		{
+			name: "HCL file",
+			config: map[string]string{"main.tf": `
+variable "env" {
+  default = "prod"
+}
+
+locals {
+  env = var.env
+}`},
+			src: `"${local.env}-app"`,
+			rng: map[string]any{
+				"filename": "main.tf",
+				"start":    map[string]int{"line": 3, "column": 13, "byte": 30},
+				"end":      map[string]int{"line": 3, "column": 19, "byte": 36},
+			},
+			want: map[string]any{
+				"value":     "prod-app",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "main.tf",
+					"start":    map[string]int{"line": 3, "column": 13, "byte": 30},
+					"end":      map[string]int{"line": 3, "column": 31, "byte": 48},
+				},
+			},
+		},
+		{
+			name:   "JSON file",
+			config: map[string]string{"main.tf.json": `{"variable": {"env": {"default": "prod"}}, "locals": {"env": "${var.env}"}}`},
+			src:    `"${local.env}-app"`,
+			rng: map[string]any{
+				"filename": "main.tf.json",
+				"start":    map[string]int{"line": 1, "column": 33, "byte": 32},
+				"end":      map[string]int{"line": 1, "column": 39, "byte": 38},
+			},
+			want: map[string]any{
+				"value":     "prod-app",
+				"unknown":   false,
+				"sensitive": false,
+				"ephemeral": false,
+				"range": map[string]any{
+					"filename": "main.tf.json",
+					"start":    map[string]int{"line": 1, "column": 33, "byte": 32},
+					"end":      map[string]int{"line": 1, "column": 51, "byte": 50},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := tester.NewRunner(test.config)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			rng, err := ast.InterfaceToValue(test.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := rego.BuiltinContext{}
+			got, err := EvalAtFunc(runner).Impl(ctx, ast.StringTerm(test.src), ast.NewTerm(rng))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := ast.InterfaceToValue(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphFunc(t *testing.T) {
+	config := `variable "env" {}
+
+locals {
+  name = "app-${var.env}"
+}
+
+data "aws_ami" "main" {}
+
+resource "aws_instance" "main" {
+  ami  = data.aws_ami.main.id
+  tags = { Name = local.name }
+
+  depends_on = [module.vpc]
+}
+
+module "vpc" {
+  source = "./vpc"
+}
+
+output "id" {
+  value = aws_instance.main.id
+}`
+
+	rng := func(line, column, byte, endColumn int) map[string]any {
+		return map[string]any{
+			"filename": "main.tf",
+			"start":    map[string]int{"line": line, "column": column, "byte": byte},
+			"end":      map[string]int{"line": line, "column": endColumn, "byte": byte + endColumn - column},
+		}
+	}
+
+	want := map[string]any{
+		"nodes": []map[string]any{
+			{"address": "var.env", "kind": "variable", "decl_range": rng(1, 1, 0, 15)},
+			{"address": "local.name", "kind": "local", "decl_range": rng(4, 3, 30, 7)},
+			{"address": "data.aws_ami.main", "kind": "data", "decl_range": rng(7, 1, 57, 22)},
+			{"address": "aws_instance.main", "kind": "resource", "decl_range": rng(9, 1, 83, 31)},
+			{"address": "module.vpc", "kind": "module", "decl_range": rng(16, 1, 209, 13)},
+			{"address": "output.id", "kind": "output", "decl_range": rng(20, 1, 246, 12)},
+		},
+		"edges": []map[string]any{
+			{"from": "local.name", "to": "var.env", "depends_on": false, "range": rng(4, 17, 44, 24)},
+			{"from": "aws_instance.main", "to": "data.aws_ami.main", "depends_on": false, "range": rng(10, 10, 125, 30)},
+			{"from": "aws_instance.main", "to": "local.name", "depends_on": false, "range": rng(11, 19, 164, 29)},
+			{"from": "aws_instance.main", "to": "module.vpc", "depends_on": true, "range": rng(13, 17, 194, 27)},
+			{"from": "output.id", "to": "aws_instance.main", "depends_on": false, "range": rng(21, 11, 270, 31)},
+		},
+	}
+
+	runner, diags := tester.NewRunner(map[string]string{"main.tf": config})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	ctx := rego.BuiltinContext{}
+	got, err := DependencyGraphFunc(runner).Impl(ctx, []*ast.Term{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantValue, err := ast.InterfaceToValue(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantValue.String(), got.Value.String()); diff != "" {
+		t.Error(diff)
+	}
+}
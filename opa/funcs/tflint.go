@@ -11,6 +11,8 @@ import (
 type Issue struct {
 	Message string
 	Range   hcl.Range
+	// Fix is the text to replace the range with. Nil if the issue cannot be fixed.
+	Fix *string
 }
 
 // issue (object<msg: string, range: range>) message and source range
@@ -22,6 +24,16 @@ var issueTy = types.NewObject(
 	nil,
 )
 
+// issue_with_fix (object<msg: string, range: range, fix: string>) message, source range, and replacement text
+var issueWithFixTy = types.NewObject(
+	[]*types.StaticProperty{
+		types.NewStaticProperty("msg", types.S),
+		types.NewStaticProperty("range", rangeTy),
+		types.NewStaticProperty("fix", types.S),
+	},
+	nil,
+)
+
 // tflint.issue: issue := tflint.issue(msg, range)
 //
 // Returns issue object
@@ -50,6 +62,36 @@ func IssueFunc() *Function2 {
 	}
 }
 
+// tflint.issue_with_fix: issue := tflint.issue_with_fix(msg, range, fix)
+//
+// Returns issue object that can be fixed automatically
+//
+//	msg   (string) message
+//	range (range)  source range
+//	fix   (string) text to replace the range with
+//
+// Returns:
+//
+//	issue (issue_with_fix) issue object
+func IssueWithFixFunc() *Function3 {
+	return &Function3{
+		Function: Function{
+			Decl: &rego.Function{
+				Name:    "tflint.issue_with_fix",
+				Decl:    types.NewFunction(types.Args(types.S, rangeTy, types.S), issueWithFixTy),
+				Memoize: true,
+			},
+		},
+		Impl: func(_ rego.BuiltinContext, msgArg *ast.Term, rngArg *ast.Term, fixArg *ast.Term) (*ast.Term, error) {
+			return ast.ObjectTerm(
+				ast.Item(ast.StringTerm("msg"), msgArg),
+				ast.Item(ast.StringTerm("range"), rngArg),
+				ast.Item(ast.StringTerm("fix"), fixArg),
+			), nil
+		},
+	}
+}
+
 // AsIssue converts JSON to an Issue object.
 func AsIssue(in any) (*Issue, error) {
 	ret, err := jsonToObject(in, "issue")
@@ -66,5 +108,15 @@ func AsIssue(in any) (*Issue, error) {
 		return nil, err
 	}
 
-	return &Issue{Message: msg, Range: rng}, nil
+	issue := &Issue{Message: msg, Range: rng}
+
+	if in, exists := ret["fix"]; exists {
+		fix, err := jsonToString(in, "issue.fix")
+		if err != nil {
+			return nil, err
+		}
+		issue.Fix = &fix
+	}
+
+	return issue, nil
 }
@@ -79,6 +79,36 @@ func TestIssueFunc(t *testing.T) {
 	}
 }
 
+func TestIssueWithFixFunc(t *testing.T) {
+	rng := map[string]any{
+		"filename": "main.tf",
+		"start":    map[string]int{"line": 1, "column": 1, "byte": 0},
+		"end":      map[string]int{"line": 1, "column": 8, "byte": 7},
+	}
+	want, err := ast.InterfaceToValue(map[string]any{
+		"msg":   "test",
+		"range": rng,
+		"fix":   "foo = 1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rngValue, err := ast.InterfaceToValue(rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := rego.BuiltinContext{}
+	got, err := IssueWithFixFunc().Impl(ctx, ast.StringTerm("test"), ast.NewTerm(rngValue), ast.StringTerm("foo = 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want.String(), got.Value.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestAsIssue(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -98,6 +128,32 @@ func TestAsIssue(t *testing.T) {
 			},
 			want: &Issue{Message: "message"},
 		},
+		{
+			name: "issue with fix",
+			input: map[string]any{
+				"msg": "message",
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]any{"line": json.Number("0"), "column": json.Number("0"), "byte": json.Number("0")},
+					"end":      map[string]any{"line": json.Number("0"), "column": json.Number("0"), "byte": json.Number("0")},
+				},
+				"fix": "foo = 1",
+			},
+			want: &Issue{Message: "message", Fix: ptr("foo = 1")},
+		},
+		{
+			name: "invalid fix type",
+			input: map[string]any{
+				"msg": "message",
+				"range": map[string]any{
+					"filename": "",
+					"start":    map[string]any{"line": json.Number("0"), "column": json.Number("0"), "byte": json.Number("0")},
+					"end":      map[string]any{"line": json.Number("0"), "column": json.Number("0"), "byte": json.Number("0")},
+				},
+				"fix": 1,
+			},
+			err: "issue.fix is not string, got int",
+		},
 		{
 			name:  "invalid type",
 			input: "",
@@ -144,3 +200,7 @@ func TestAsIssue(t *testing.T) {
 		})
 	}
 }
+
+func ptr[T any](v T) *T {
+	return &v
+}
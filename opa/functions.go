@@ -25,10 +25,28 @@ func Functions(runner tflint.Runner) []func(*rego.Rego) {
 		funcs.EphemeralResourcesFunc(runner).Rego(),
 		funcs.ActionsFunc(runner).Rego(),
 		funcs.ModuleRangeFunc(runner).Rego(),
+		funcs.FilesFunc(runner).Rego(),
+		funcs.ReadFileFunc(runner).Rego(),
+		funcs.CommentsFunc(runner).Rego(),
+		funcs.UnformattedFilesFunc(runner).Rego(),
+		funcs.LockfileFunc(runner).Rego(),
+		funcs.TfvarsFunc(runner).Rego(),
+		funcs.TestsFunc(runner).Rego(),
+		funcs.ModuleManifestFunc(runner).Rego(),
+		funcs.ResourceProviderFunc(runner).Rego(),
+		funcs.EvalFunc(runner).Rego(),
+		funcs.EvalAtFunc(runner).Rego(),
+		funcs.DependencyGraphFunc(runner).Rego(),
+		funcs.VersionConstraintParseFunc().Rego(),
+		funcs.VersionConstraintAllowsFunc().Rego(),
+		funcs.VersionConstraintPessimisticFunc().Rego(),
+		funcs.TypeConstraintFunc().Rego(),
 		funcs.ExprListFunc().Rego(),
 		funcs.ExprMapFunc().Rego(),
 		funcs.ExprCallFunc().Rego(),
+		funcs.FormatFunc().Rego(),
 		funcs.IssueFunc().Rego(),
+		funcs.IssueWithFixFunc().Rego(),
 	}
 }
 
@@ -50,51 +68,92 @@ func TesterFunctions(runner tflint.Runner) []*tester.Builtin {
 		funcs.EphemeralResourcesFunc(runner).Tester(),
 		funcs.ActionsFunc(runner).Tester(),
 		funcs.ModuleRangeFunc(runner).Tester(),
+		funcs.FilesFunc(runner).Tester(),
+		funcs.ReadFileFunc(runner).Tester(),
+		funcs.CommentsFunc(runner).Tester(),
+		funcs.UnformattedFilesFunc(runner).Tester(),
+		funcs.LockfileFunc(runner).Tester(),
+		funcs.TfvarsFunc(runner).Tester(),
+		funcs.TestsFunc(runner).Tester(),
+		funcs.ModuleManifestFunc(runner).Tester(),
+		funcs.ResourceProviderFunc(runner).Tester(),
+		funcs.EvalFunc(runner).Tester(),
+		funcs.EvalAtFunc(runner).Tester(),
+		funcs.DependencyGraphFunc(runner).Tester(),
+		funcs.FixtureFunc().Tester(),
+		funcs.VersionConstraintParseFunc().Tester(),
+		funcs.VersionConstraintAllowsFunc().Tester(),
+		funcs.VersionConstraintPessimisticFunc().Tester(),
+		funcs.TypeConstraintFunc().Tester(),
 		funcs.ExprListFunc().Tester(),
 		funcs.ExprMapFunc().Tester(),
 		funcs.ExprCallFunc().Tester(),
+		funcs.FormatFunc().Tester(),
 		funcs.IssueFunc().Tester(),
+		funcs.IssueWithFixFunc().Tester(),
+	}
+}
+
+// mockFunction is a mock function, which can be used as both a Rego option and a tester.Builtin.
+type mockFunction interface {
+	Rego() func(*rego.Rego)
+	Tester() *tester.Builtin
+}
+
+// moduleMocks returns functions that inspect the module and their mocks.
+// The mocks are provided as terraform.mock_* functions, and `with terraform.module as {...}`
+// replaces the functions with the mocks. See withModuleMocks for details.
+func moduleMocks() []moduleMock {
+	return []moduleMock{
+		{target: "terraform.resources", mock: funcs.MockFunction3(funcs.ResourcesFunc)},
+		{target: "terraform.data_sources", mock: funcs.MockFunction3(funcs.DataSourcesFunc)},
+		{target: "terraform.module_calls", mock: funcs.MockFunction2(funcs.ModuleCallsFunc)},
+		{target: "terraform.providers", mock: funcs.MockFunction2(funcs.ProvidersFunc)},
+		{target: "terraform.settings", mock: funcs.MockFunction2(funcs.SettingsFunc)},
+		{target: "terraform.variables", mock: funcs.MockFunction2(funcs.VariablesFunc)},
+		{target: "terraform.outputs", mock: funcs.MockFunction2(funcs.OutputsFunc)},
+		{target: "terraform.locals", mock: funcs.MockFunction1(funcs.LocalsFunc)},
+		{target: "terraform.moved_blocks", mock: funcs.MockFunction2(funcs.MovedBlocksFunc)},
+		{target: "terraform.imports", mock: funcs.MockFunction2(funcs.ImportsFunc)},
+		{target: "terraform.checks", mock: funcs.MockFunction2(funcs.ChecksFunc)},
+		{target: "terraform.removed_blocks", mock: funcs.MockFunction2(funcs.RemovedBlocksFunc)},
+		{target: "terraform.ephemeral_resources", mock: funcs.MockFunction3(funcs.EphemeralResourcesFunc)},
+		{target: "terraform.actions", mock: funcs.MockFunction3(funcs.ActionsFunc)},
+		{target: "terraform.module_range", mock: funcs.MockFunctionDyn(funcs.ModuleRangeFunc)},
+		{target: "terraform.files", mock: funcs.MockFunctionDyn(funcs.FilesFunc)},
+		{target: "terraform.read_file", mock: funcs.MockFunction1(funcs.ReadFileFunc)},
+		{target: "terraform.comments", mock: funcs.MockFunctionDyn(funcs.CommentsFunc)},
+		{target: "terraform.unformatted_files", mock: funcs.MockFunctionDyn(funcs.UnformattedFilesFunc)},
+		{target: "terraform.lockfile", mock: funcs.MockFunctionDyn(funcs.LockfileFunc)},
+		{target: "terraform.tfvars", mock: funcs.MockFunctionDyn(funcs.TfvarsFunc)},
+		{target: "terraform.tests", mock: funcs.MockFunction1(funcs.TestsFunc)},
+		{target: "terraform.module_manifest", mock: funcs.MockFunctionDyn(funcs.ModuleManifestFunc)},
+		{target: "terraform.resource_provider", mock: funcs.MockFunction2(funcs.ResourceProviderFunc)},
+		{target: "terraform.eval", mock: funcs.MockFunction1(funcs.EvalFunc)},
+		{target: "terraform.eval_at", mock: funcs.MockFunction2(funcs.EvalAtFunc)},
+		{target: "terraform.dependency_graph", mock: funcs.MockFunctionDyn(funcs.DependencyGraphFunc)},
 	}
 }
 
 // MockFunctions return mocks for custom functions as Rego options.
 // Mock functions are usually not needed outside of testing,
-// but are provided for compilation.
+// but are provided for compilation. Test-only functions such as
+// terraform.fixture are also declared here, but always return an error.
 func MockFunctions() []func(*rego.Rego) {
-	return []func(*rego.Rego){
-		funcs.MockFunction3(funcs.ResourcesFunc).Rego(),
-		funcs.MockFunction3(funcs.DataSourcesFunc).Rego(),
-		funcs.MockFunction2(funcs.ModuleCallsFunc).Rego(),
-		funcs.MockFunction2(funcs.ProvidersFunc).Rego(),
-		funcs.MockFunction2(funcs.SettingsFunc).Rego(),
-		funcs.MockFunction2(funcs.VariablesFunc).Rego(),
-		funcs.MockFunction2(funcs.OutputsFunc).Rego(),
-		funcs.MockFunction1(funcs.LocalsFunc).Rego(),
-		funcs.MockFunction2(funcs.MovedBlocksFunc).Rego(),
-		funcs.MockFunction2(funcs.ImportsFunc).Rego(),
-		funcs.MockFunction2(funcs.ChecksFunc).Rego(),
-		funcs.MockFunction2(funcs.RemovedBlocksFunc).Rego(),
-		funcs.MockFunction3(funcs.EphemeralResourcesFunc).Rego(),
-		funcs.MockFunction3(funcs.ActionsFunc).Rego(),
+	ret := []func(*rego.Rego){
+		funcs.TestOnlyFunction1(funcs.FixtureFunc()).Rego(),
 	}
+	for _, mock := range moduleMocks() {
+		ret = append(ret, mock.mock.Rego())
+	}
+	return ret
 }
 
 // TesterMockFunctions return mocks for custom functions.
 func TesterMockFunctions() []*tester.Builtin {
-	return []*tester.Builtin{
-		funcs.MockFunction3(funcs.ResourcesFunc).Tester(),
-		funcs.MockFunction3(funcs.DataSourcesFunc).Tester(),
-		funcs.MockFunction2(funcs.ModuleCallsFunc).Tester(),
-		funcs.MockFunction2(funcs.ProvidersFunc).Tester(),
-		funcs.MockFunction2(funcs.SettingsFunc).Tester(),
-		funcs.MockFunction2(funcs.VariablesFunc).Tester(),
-		funcs.MockFunction2(funcs.OutputsFunc).Tester(),
-		funcs.MockFunction1(funcs.LocalsFunc).Tester(),
-		funcs.MockFunction2(funcs.MovedBlocksFunc).Tester(),
-		funcs.MockFunction2(funcs.ImportsFunc).Tester(),
-		funcs.MockFunction2(funcs.ChecksFunc).Tester(),
-		funcs.MockFunction2(funcs.RemovedBlocksFunc).Tester(),
-		funcs.MockFunction3(funcs.EphemeralResourcesFunc).Tester(),
-		funcs.MockFunction3(funcs.ActionsFunc).Tester(),
+	ret := []*tester.Builtin{}
+	for _, mock := range moduleMocks() {
+		ret = append(ret, mock.mock.Tester())
 	}
+	return ret
 }
@@ -0,0 +1,79 @@
+// Package lang provides the functions available in Terraform expressions evaluated by the ruleset.
+// The functions are the same as those of TFLint, which are ported from Terraform.
+// The test runner uses pure functions, so update docs/testing.md when changing the list of impure functions.
+package lang
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tflintlang "github.com/terraform-linters/tflint/terraform/lang"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// functions are Terraform functions as evaluated by TFLint.
+var functions = (&tflintlang.Scope{}).Functions()
+
+// impureFunctions are Terraform functions that read the filesystem or depend on the time.
+var impureFunctions = []string{
+	"bcrypt", "file", "filebase64", "filebase64sha256", "filebase64sha512", "fileexists",
+	"filemd5", "fileset", "filesha1", "filesha256", "filesha512", "plantimestamp",
+	"templatefile", "timestamp", "uuid",
+}
+
+// UnknownFunc is a function that accepts any arguments and always returns an unknown value.
+// Marks of the arguments are applied to the result.
+var UnknownFunc = function.New(&function.Spec{
+	VarParam: &function.Parameter{
+		Name:             "args",
+		Type:             cty.DynamicPseudoType,
+		AllowUnknown:     true,
+		AllowNull:        true,
+		AllowDynamicType: true,
+	},
+	Type: function.StaticReturnType(cty.DynamicPseudoType),
+	Impl: func([]cty.Value, cty.Type) (cty.Value, error) {
+		return cty.DynamicVal, nil
+	},
+})
+
+// Functions returns functions for evaluating the given expressions in the same way as TFLint.
+// Calls to provider-defined functions return unknown values, as TFLint does.
+// Calls to functions that do not exist in Terraform are errors, as in Terraform.
+func Functions(exprs ...hcl.Expression) map[string]function.Function {
+	ret := maps.Clone(functions)
+
+	for _, expr := range exprs {
+		expr, ok := hcl.UnwrapExpression(expr).(hclsyntax.Expression)
+		if !ok {
+			continue
+		}
+		hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			call, ok := node.(*hclsyntax.FunctionCallExpr)
+			if !ok {
+				return nil
+			}
+			if _, exists := ret[call.Name]; !exists && strings.HasPrefix(call.Name, "provider::") {
+				ret[call.Name] = UnknownFunc
+			}
+			return nil
+		})
+	}
+
+	return ret
+}
+
+// PureFunctions is the same as Functions, but calls to functions that read the filesystem
+// or depend on the time return unknown values, as TFLint does for values it cannot determine.
+// This is used by the test runner, as mock files are not on the filesystem and tests should be deterministic.
+func PureFunctions(exprs ...hcl.Expression) map[string]function.Function {
+	ret := Functions(exprs...)
+	for _, name := range impureFunctions {
+		ret[name] = UnknownFunc
+		ret["core::"+name] = UnknownFunc
+	}
+	return ret
+}
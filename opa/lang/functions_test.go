@@ -0,0 +1,282 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terraform-linters/tflint-plugin-sdk/terraform/lang/marks"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestPureFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want cty.Value
+		err  string
+	}{
+		{
+			name: "supported function",
+			expr: `upper("foo")`,
+			want: cty.StringVal("FOO"),
+		},
+		{
+			name: "try",
+			expr: `try(tonumber("foo"), "bar")`,
+			want: cty.StringVal("bar"),
+		},
+		{
+			name: "replace",
+			expr: `replace("foo-bar", "-", "_")`,
+			want: cty.StringVal("foo_bar"),
+		},
+		{
+			name: "replace with regex",
+			expr: `replace("foo-1-2", "/-[0-9]/", "")`,
+			want: cty.StringVal("foo"),
+		},
+		{
+			name: "startswith",
+			expr: `startswith("foo-bar", "foo")`,
+			want: cty.True,
+		},
+		{
+			name: "cidrsubnet",
+			expr: `cidrsubnet("10.0.0.0/16", 8, 1)`,
+			want: cty.StringVal("10.0.1.0/24"),
+		},
+		{
+			name: "cidrsubnet IPv6",
+			expr: `cidrsubnet("fd00:fd12:3456:7890::/56", 16, 162)`,
+			want: cty.StringVal("fd00:fd12:3456:7800:a200::/72"),
+		},
+		{
+			name: "cidrsubnet out of range",
+			expr: `cidrsubnet("10.0.0.0/16", 8, 256)`,
+			err:  `main.tf:1,1-12: Error in function call; Call to function "cidrsubnet" failed: prefix extension of 8 does not accommodate a subnet numbered 256.`,
+		},
+		{
+			name: "cidrhost",
+			expr: `cidrhost("10.12.112.0/20", -2)`,
+			want: cty.StringVal("10.12.127.254"),
+		},
+		{
+			name: "cidrnetmask",
+			expr: `cidrnetmask("172.16.0.0/12")`,
+			want: cty.StringVal("255.240.0.0"),
+		},
+		{
+			name: "length of string",
+			expr: `length("abc")`,
+			want: cty.NumberIntVal(3),
+		},
+		{
+			name: "length of multibyte string",
+			expr: `length("日本語")`,
+			want: cty.NumberIntVal(3),
+		},
+		{
+			name: "length of tuple",
+			expr: `length(["a", "b"])`,
+			want: cty.NumberIntVal(2),
+		},
+		{
+			name: "length of object",
+			expr: `length({ a = 1, b = "2" })`,
+			want: cty.NumberIntVal(2),
+		},
+		{
+			name: "length of list",
+			expr: `length(tolist(["a", "b", "c"]))`,
+			want: cty.NumberIntVal(3),
+		},
+		{
+			name: "length of unknown string",
+			expr: `length(var.unknown)`,
+			want: cty.UnknownVal(cty.Number).Refine().NotNull().NumberRangeLowerBound(cty.NumberIntVal(0), true).NewValue(),
+		},
+		{
+			name: "length of unknown value",
+			expr: `length(var.dynamic)`,
+			want: cty.UnknownVal(cty.Number).RefineNotNull(),
+		},
+		{
+			name: "length of number",
+			expr: `length(1)`,
+			err:  `main.tf:1,1-8: Error in function call; Call to function "length" failed: argument must be a string, a collection type, or a structural type.`,
+		},
+		{
+			name: "lookup without default",
+			expr: `lookup({ a = "foo" }, "a")`,
+			want: cty.StringVal("foo"),
+		},
+		{
+			name: "lookup with default",
+			expr: `lookup({ a = "foo" }, "b", "bar")`,
+			want: cty.StringVal("bar"),
+		},
+		{
+			name: "lookup in map",
+			expr: `lookup(tomap({ a = "foo" }), "a")`,
+			want: cty.StringVal("foo"),
+		},
+		{
+			name: "lookup in unknown map",
+			expr: `lookup(var.unknown_map, "a")`,
+			want: cty.UnknownVal(cty.String),
+		},
+		{
+			name: "lookup missing key",
+			expr: `lookup(tomap({ a = "foo" }), "b")`,
+			err:  `main.tf:1,1-8: Error in function call; Call to function "lookup" failed: lookup failed to find key "b".`,
+		},
+		{
+			name: "one",
+			expr: `one(["foo"])`,
+			want: cty.StringVal("foo"),
+		},
+		{
+			name: "sum",
+			expr: `sum([1, 2, 3])`,
+			want: cty.NumberIntVal(6),
+		},
+		{
+			name: "base64encode",
+			expr: `base64encode("foo")`,
+			want: cty.StringVal("Zm9v"),
+		},
+		{
+			name: "cidrsubnets",
+			expr: `cidrsubnets("10.0.0.0/16", 8, 8)`,
+			want: cty.ListVal([]cty.Value{cty.StringVal("10.0.0.0/24"), cty.StringVal("10.0.1.0/24")}),
+		},
+		{
+			name: "sha256 with sensitive argument",
+			expr: `sha256(var.secret)`,
+			want: cty.StringVal("2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae").Mark(marks.Sensitive),
+		},
+		{
+			name: "nonsensitive",
+			expr: `nonsensitive(var.secret)`,
+			want: cty.StringVal("foo"),
+		},
+		{
+			name: "core namespace",
+			expr: `core::upper("foo")`,
+			want: cty.StringVal("FOO"),
+		},
+		{
+			name: "file",
+			expr: `file("foo.txt")`,
+			want: cty.DynamicVal,
+		},
+		{
+			name: "file in arguments",
+			expr: `merge({ foo = "bar" }, { baz = file("baz.txt") })`,
+			want: cty.ObjectVal(map[string]cty.Value{"foo": cty.StringVal("bar"), "baz": cty.DynamicVal}),
+		},
+		{
+			name: "file in core namespace",
+			expr: `core::file("foo.txt")`,
+			want: cty.DynamicVal,
+		},
+		{
+			name: "timestamp in template",
+			expr: `"${timestamp()}-bar"`,
+			want: cty.UnknownVal(cty.String).Refine().NotNull().StringPrefixFull("").NewValue(),
+		},
+		{
+			name: "file with sensitive argument",
+			expr: `file(var.secret)`,
+			want: cty.DynamicVal.Mark(marks.Sensitive),
+		},
+		{
+			name: "provider-defined function",
+			expr: `provider::aws::arn_parse("arn:aws:iam::123456789012:user/foo")`,
+			want: cty.DynamicVal,
+		},
+		{
+			name: "unknown function",
+			expr: `nosuchfunc("bar")`,
+			err:  `main.tf:1,1-11: Call to unknown function; There is no function named "nosuchfunc".`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(test.expr), "main.tf", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			got, diags := expr.Value(&hcl.EvalContext{
+				Variables: map[string]cty.Value{
+					"var": cty.ObjectVal(map[string]cty.Value{
+						"unknown":     cty.UnknownVal(cty.String),
+						"dynamic":     cty.DynamicVal,
+						"unknown_map": cty.UnknownVal(cty.Map(cty.String)),
+						"secret":      cty.StringVal("foo").Mark(marks.Sensitive),
+					}),
+				},
+				Functions: PureFunctions(expr),
+			})
+			if diags.HasErrors() {
+				if diags.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, diags.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			if diff := cmp.Diff(test.want.GoString(), got.GoString()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want cty.Value
+	}{
+		{
+			name: "pure function",
+			expr: `upper("foo")`,
+			want: cty.StringVal("FOO"),
+		},
+		{
+			name: "filesystem function",
+			expr: `fileexists("functions.go")`,
+			want: cty.True,
+		},
+		{
+			name: "provider-defined function",
+			expr: `provider::aws::arn_parse("arn:aws:iam::123456789012:user/foo")`,
+			want: cty.DynamicVal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(test.expr), "main.tf", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			got, diags := expr.Value(&hcl.EvalContext{Functions: Functions(expr)})
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			if diff := cmp.Diff(test.want.GoString(), got.GoString()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
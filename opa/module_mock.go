@@ -0,0 +1,153 @@
+package opa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// moduleMockPackage is the package of the wrappers for `with terraform.module as {...}`.
+const moduleMockPackage = "tflint_opa_module_mock"
+
+// moduleRef is the target of `with terraform.module as {...}`. It is not a function,
+// but a marker replaced by withModuleMocks, so it is not registered in the ruleset.
+var moduleRef = ast.MustParseRef("terraform.module")
+
+// moduleMock is a pair of a function that inspects the module and its mock.
+type moduleMock struct {
+	// target is the name of the function replaced by the mock, e.g. "terraform.resources".
+	target string
+	// mock is the mock function that takes sources as the last argument, e.g. terraform.mock_resources.
+	mock mockFunction
+}
+
+// withModuleMocks returns modules in which `with terraform.module as {...}` replaces
+// all functions that inspect the module with their mocks in moduleMocks.
+//
+// Built-in functions cannot see values replaced by the "with" keyword, and functions replaced
+// by the "with" keyword are not available inside replacement functions. So the sources are
+// passed to the mocks via the data document by Rego wrappers. For example,
+// `with terraform.module as {...}` is rewritten as:
+//
+//	with data.tflint_opa_module_mock.sources as {...}
+//	with terraform.resources as data.tflint_opa_module_mock.terraform_resources
+//	...
+//
+// and the wrapper calls the mock with the sources:
+//
+//	terraform_resources(a0, a1, a2) := terraform.mock_resources(a0, a1, a2, data.tflint_opa_module_mock.sources)
+//
+// Functions replaced explicitly in the same expression are not overwritten.
+// If no module uses terraform.module, the passed modules are returned as is.
+func withModuleMocks(modules map[string]*ast.Module) (map[string]*ast.Module, error) {
+	mocks := moduleMocks()
+
+	ret := map[string]*ast.Module{}
+	rewritten := false
+	for name, module := range modules {
+		if !usesModuleMock(module) {
+			ret[name] = module
+			continue
+		}
+
+		module = module.Copy()
+		ast.WalkExprs(module, func(expr *ast.Expr) bool {
+			for i, with := range expr.With {
+				if ref, ok := with.Target.Value.(ast.Ref); !ok || !ref.Equal(moduleRef) {
+					continue
+				}
+				withs := append(expr.With[:i:i], expr.With[i+1:]...)
+				expr.With = appendModuleMocks(withs, mocks, with)
+				break
+			}
+			return false
+		})
+		ret[name] = module
+		rewritten = true
+	}
+
+	if !rewritten {
+		return modules, nil
+	}
+
+	// The filename is left empty so that the wrappers are not included in the coverage report
+	wrappers, err := ast.ParseModuleWithOpts("", moduleMockSource(mocks), ast.ParserOptions{RegoVersion: ast.RegoV1})
+	if err != nil {
+		return nil, err
+	}
+	ret[moduleMockPackage+".rego"] = wrappers
+
+	return ret, nil
+}
+
+// wrapper returns the name of the wrapper rule, e.g. "terraform_resources" for terraform.resources.
+func (m moduleMock) wrapper() string {
+	return strings.ReplaceAll(m.target, ".", "_")
+}
+
+func moduleMockSource(mocks []moduleMock) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", moduleMockPackage)
+
+	for _, mock := range mocks {
+		decl := mock.mock.Tester().Decl
+		// Mock functions take sources as the last argument
+		argc := len(decl.Decl.FuncArgs().Args) - 1
+
+		if argc == 0 {
+			// Functions without arguments are replaced with values
+			fmt.Fprintf(&b, "%s := %s(data.%s.sources)\n", mock.wrapper(), decl.Name, moduleMockPackage)
+			continue
+		}
+
+		args := make([]string, argc)
+		for i := range args {
+			args[i] = fmt.Sprintf("a%d", i)
+		}
+		fmt.Fprintf(&b, "%s(%s) := %s(%s, data.%s.sources)\n", mock.wrapper(), strings.Join(args, ", "), decl.Name, strings.Join(args, ", "), moduleMockPackage)
+	}
+
+	return b.String()
+}
+
+func usesModuleMock(module *ast.Module) bool {
+	found := false
+	ast.WalkExprs(module, func(expr *ast.Expr) bool {
+		for _, with := range expr.With {
+			if ref, ok := with.Target.Value.(ast.Ref); ok && ref.Equal(moduleRef) {
+				found = true
+			}
+		}
+		return found
+	})
+	return found
+}
+
+// appendModuleMocks appends mocks to the with modifiers. Functions already replaced
+// explicitly are not overwritten.
+func appendModuleMocks(withs []*ast.With, mocks []moduleMock, module *ast.With) []*ast.With {
+	loc := module.Location
+
+	replaced := map[string]bool{}
+	for _, with := range withs {
+		replaced[with.Target.String()] = true
+	}
+
+	sources := ast.NewTerm(ast.MustParseRef(fmt.Sprintf("data.%s.sources", moduleMockPackage)))
+	sources.Location = loc
+	withs = append(withs, &ast.With{Target: sources, Value: module.Value.Copy(), Location: loc})
+
+	for _, mock := range mocks {
+		if replaced[mock.target] {
+			continue
+		}
+
+		target := ast.NewTerm(ast.MustParseRef(mock.target))
+		target.Location = loc
+		value := ast.NewTerm(ast.MustParseRef(fmt.Sprintf("data.%s.%s", moduleMockPackage, mock.wrapper())))
+		value.Location = loc
+		withs = append(withs, &ast.With{Target: target, Value: value, Location: loc})
+	}
+	return withs
+}
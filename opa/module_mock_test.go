@@ -0,0 +1,84 @@
+package opa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/types"
+)
+
+func TestModuleMocks(t *testing.T) {
+	builtins := map[string]*ast.Builtin{}
+	for _, builtin := range TesterFunctions(nil) {
+		builtins[builtin.Decl.Name] = builtin.Decl
+	}
+
+	mocked := map[string]bool{}
+	for _, mock := range moduleMocks() {
+		mocked[mock.target] = true
+
+		target, exists := builtins[mock.target]
+		if !exists {
+			t.Errorf("%s is not a function", mock.target)
+			continue
+		}
+		decl := mock.mock.Tester().Decl
+		// The mock takes the same arguments as the target, followed by the sources.
+		args := decl.Decl.FuncArgs().Args
+		if types.Compare(types.NewFunction(args[:len(args)-1], decl.Decl.Result()), target.Decl) != 0 {
+			t.Errorf("%s is not a mock of %s: %s", decl.Name, mock.target, decl.Decl)
+		}
+	}
+
+	// All functions that inspect the module must be mocked.
+	notInspecting := map[string]bool{
+		"terraform.fixture":                        true,
+		"terraform.version_constraint_parse":       true,
+		"terraform.version_constraint_allows":      true,
+		"terraform.version_constraint_pessimistic": true,
+		"terraform.type_constraint":                true,
+	}
+	for name := range builtins {
+		if strings.HasPrefix(name, "terraform.") && !notInspecting[name] && !mocked[name] {
+			t.Errorf("%s is not mocked", name)
+		}
+	}
+}
+
+func TestWithModuleMocks(t *testing.T) {
+	module := ast.MustParseModuleWithOpts(`
+package tflint
+
+test_deny if {
+	deny with terraform.module as {"main.tf": ""} with terraform.files as []
+}`, ast.ParserOptions{RegoVersion: ast.RegoV1})
+
+	got, err := withModuleMocks(map[string]*ast.Module{"main_test.rego": module})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := got[moduleMockPackage+".rego"]; !exists {
+		t.Fatal("wrappers are not added")
+	}
+
+	withs := map[string]string{}
+	for _, with := range got["main_test.rego"].Rules[0].Body[0].With {
+		withs[with.Target.String()] = with.Value.String()
+	}
+	if _, exists := withs["terraform.module"]; exists {
+		t.Error("terraform.module should be replaced")
+	}
+	if withs["data.tflint_opa_module_mock.sources"] != `{"main.tf": ""}` {
+		t.Errorf("sources should be passed, but got %s", withs["data.tflint_opa_module_mock.sources"])
+	}
+	if withs["terraform.resources"] != "data.tflint_opa_module_mock.terraform_resources" {
+		t.Errorf("terraform.resources should be mocked, but got %s", withs["terraform.resources"])
+	}
+	if withs["terraform.files"] != "[]" {
+		t.Errorf("functions replaced explicitly should not be overwritten, but got %s", withs["terraform.files"])
+	}
+	if module.Rules[0].Body[0].With[0].Target.String() != "terraform.module" {
+		t.Error("the passed module should not be modified")
+	}
+}
@@ -73,6 +73,16 @@ func (r *Rule) Check(runner tflint.Runner) error {
 	}
 
 	for _, issue := range issues {
+		if issue.Fix != nil {
+			fix := *issue.Fix
+			if err := runner.EmitIssueWithFix(r, issue.Message, issue.Range, func(f tflint.Fixer) error {
+				return f.ReplaceText(issue.Range, fix)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if err := runner.EmitIssue(r, issue.Message, issue.Range); err != nil {
 			return err
 		}
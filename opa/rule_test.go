@@ -667,3 +667,50 @@ resource "aws_instance" "main" {
 		})
 	}
 }
+
+func TestCheck_fix_unformatted_files(t *testing.T) {
+	fs := memoryfs.New()
+	policy := `
+package tflint
+
+import rego.v1
+
+deny_unformatted contains issue if {
+	files := terraform.unformatted_files()
+	diff := files[_].diffs[_]
+
+	issue := tflint.issue_with_fix("not formatted", diff.range, diff.formatted)
+}`
+	fs.WriteFile("main.rego", []byte(policy), 0o644)
+
+	ret, err := loader.NewFileLoader().WithFS(fs).Filtered([]string{"."}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngine(ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := NewRule(&ast.Rule{Head: &ast.Head{Name: "deny_unformatted"}}, engine)
+
+	runner := helper.TestRunner(t, map[string]string{"main.tf": `
+resource "aws_instance" "main" {
+instance_type = "t2.micro"
+}`})
+
+	if err := rule.Check(runner); err != nil {
+		t.Fatal(err)
+	}
+
+	helper.AssertIssues(t, helper.Issues{
+		{
+			Rule:    rule,
+			Message: "not formatted",
+			Range:   hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 3, Column: 1}, End: hcl.Pos{Line: 3, Column: 27}},
+		},
+	}, runner.Issues)
+	helper.AssertChanges(t, map[string]string{"main.tf": `
+resource "aws_instance" "main" {
+  instance_type = "t2.micro"
+}`}, runner.Changes())
+}
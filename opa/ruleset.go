@@ -3,6 +3,7 @@ package opa
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/open-policy-agent/opa/v1/loader"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
@@ -90,5 +91,33 @@ func (r *RuleSet) ApplyConfig(body *hclext.BodyContent) error {
 		}
 	}
 
-	return r.BuiltinRuleSet.ApplyGlobalConfig(r.globalConfig)
+	// If TFLINT_OPA_COVERAGE or TFLINT_OPA_COVERAGE_THRESHOLD is set in test mode,
+	// report the coverage after all tests are run
+	var coverage *coverageReporter
+	coverageDir := os.Getenv("TFLINT_OPA_COVERAGE")
+	coverageThreshold := os.Getenv("TFLINT_OPA_COVERAGE_THRESHOLD")
+	if testMode && (coverageDir != "" || coverageThreshold != "") {
+		var threshold float64
+		if coverageThreshold != "" {
+			threshold, err = strconv.ParseFloat(coverageThreshold, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse TFLINT_OPA_COVERAGE_THRESHOLD; %w", err)
+			}
+			if !(threshold >= 0 && threshold <= 100) {
+				return fmt.Errorf("TFLINT_OPA_COVERAGE_THRESHOLD must be between 0 and 100, but got %s", coverageThreshold)
+			}
+		}
+		coverage = newCoverageReporter(engine, policyDir, coverageDir, threshold)
+	}
+
+	if err := r.BuiltinRuleSet.ApplyGlobalConfig(r.globalConfig); err != nil {
+		return err
+	}
+	// Rules are run in the order of EnabledRules, so the coverage is reported last.
+	// It is appended after the rules are filtered so that it is not affected by
+	// the rule configs and --only, whichever tests are enabled.
+	if coverage != nil {
+		r.EnabledRules = append(r.EnabledRules, coverage)
+	}
+	return nil
 }
@@ -19,12 +19,14 @@ func TestApplyConfig(t *testing.T) {
 	}
 
 	tests := []struct {
-		name   string
-		config *hclext.BodyContent
-		root   string
-		env    map[string]string
-		want   []string
-		err    bool
+		name    string
+		config  *hclext.BodyContent
+		root    string
+		global  *tflint.Config
+		env     map[string]string
+		want    []string
+		enabled []string
+		err     bool
 	}{
 		{
 			name: "rules exists",
@@ -53,6 +55,88 @@ func TestApplyConfig(t *testing.T) {
 			},
 			want: []string{"opa_test_deny_not_snake_case", "opa_test_not_deny_t2_micro"},
 		},
+		{
+			name: "coverage enabled",
+			config: &hclext.BodyContent{
+				Attributes: hclext.Attributes{
+					"policy_dir": &hclext.Attribute{
+						Name: "policy_dir",
+						Expr: hcl.StaticExpr(cty.StringVal(filepath.Join(cwd, "test-fixtures", "config", "root-exists", ".tflint.d", "policies")), hcl.Range{}),
+					},
+				},
+			},
+			env: map[string]string{
+				"TFLINT_OPA_TEST":               "true",
+				"TFLINT_OPA_COVERAGE_THRESHOLD": "80",
+			},
+			want:    []string{"opa_test_deny_not_snake_case", "opa_test_not_deny_t2_micro"},
+			enabled: []string{"opa_test_deny_not_snake_case", "opa_test_not_deny_t2_micro", "opa_coverage"},
+		},
+		{
+			name: "coverage enabled with only",
+			config: &hclext.BodyContent{
+				Attributes: hclext.Attributes{
+					"policy_dir": &hclext.Attribute{
+						Name: "policy_dir",
+						Expr: hcl.StaticExpr(cty.StringVal(filepath.Join(cwd, "test-fixtures", "config", "root-exists", ".tflint.d", "policies")), hcl.Range{}),
+					},
+				},
+			},
+			global: &tflint.Config{Only: []string{"opa_test_not_deny_t2_micro"}},
+			env: map[string]string{
+				"TFLINT_OPA_TEST":               "true",
+				"TFLINT_OPA_COVERAGE_THRESHOLD": "80",
+			},
+			want:    []string{"opa_test_deny_not_snake_case", "opa_test_not_deny_t2_micro"},
+			enabled: []string{"opa_test_not_deny_t2_micro", "opa_coverage"},
+		},
+		{
+			name: "coverage without test mode",
+			config: &hclext.BodyContent{
+				Attributes: hclext.Attributes{
+					"policy_dir": &hclext.Attribute{
+						Name: "policy_dir",
+						Expr: hcl.StaticExpr(cty.StringVal(filepath.Join(cwd, "test-fixtures", "config", "root-exists", ".tflint.d", "policies")), hcl.Range{}),
+					},
+				},
+			},
+			env: map[string]string{
+				"TFLINT_OPA_COVERAGE": "coverage",
+			},
+			want: []string{"opa_deny_not_snake_case", "opa_deny_not_t2_micro"},
+		},
+		{
+			name: "invalid coverage threshold",
+			config: &hclext.BodyContent{
+				Attributes: hclext.Attributes{
+					"policy_dir": &hclext.Attribute{
+						Name: "policy_dir",
+						Expr: hcl.StaticExpr(cty.StringVal(filepath.Join(cwd, "test-fixtures", "config", "root-exists", ".tflint.d", "policies")), hcl.Range{}),
+					},
+				},
+			},
+			env: map[string]string{
+				"TFLINT_OPA_TEST":               "true",
+				"TFLINT_OPA_COVERAGE_THRESHOLD": "high",
+			},
+			err: true,
+		},
+		{
+			name: "coverage threshold out of range",
+			config: &hclext.BodyContent{
+				Attributes: hclext.Attributes{
+					"policy_dir": &hclext.Attribute{
+						Name: "policy_dir",
+						Expr: hcl.StaticExpr(cty.StringVal(filepath.Join(cwd, "test-fixtures", "config", "root-exists", ".tflint.d", "policies")), hcl.Range{}),
+					},
+				},
+			},
+			env: map[string]string{
+				"TFLINT_OPA_TEST":               "true",
+				"TFLINT_OPA_COVERAGE_THRESHOLD": "120",
+			},
+			err: true,
+		},
 		{
 			name: "policy dir not exists, but the dir is default",
 			root: filepath.Join(cwd, "test-fixtures", "config", "root-not-exists", ".tflint.d", "policies"),
@@ -87,7 +171,11 @@ func TestApplyConfig(t *testing.T) {
 				t.Setenv(k, v)
 			}
 
-			ruleset := &RuleSet{config: &Config{}, globalConfig: &tflint.Config{}}
+			global := test.global
+			if global == nil {
+				global = &tflint.Config{}
+			}
+			ruleset := &RuleSet{config: &Config{}, globalConfig: global}
 			err := ruleset.ApplyConfig(test.config)
 			if err != nil {
 				if test.err {
@@ -106,6 +194,16 @@ func TestApplyConfig(t *testing.T) {
 			if diff := cmp.Diff(test.want, got); diff != "" {
 				t.Error(diff)
 			}
+
+			if test.enabled != nil {
+				enabled := make([]string, len(ruleset.EnabledRules))
+				for i, r := range ruleset.EnabledRules {
+					enabled[i] = r.Name()
+				}
+				if diff := cmp.Diff(test.enabled, enabled); diff != "" {
+					t.Error(diff)
+				}
+			}
 		})
 	}
 }
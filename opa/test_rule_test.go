@@ -3,6 +3,7 @@ package opa
 import (
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/liamg/memoryfs"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/loader"
@@ -88,7 +89,12 @@ deny_not_t2_micro contains issue if {
 			want: helper.Issues{
 				{
 					Rule:    &TestRule{},
-					Message: "test failed",
+					Message: "test failed: data.tflint.test_not_deny_t2_micro (0s)",
+					Range: hcl.Range{
+						Filename: "main_test.rego",
+						Start:    hcl.Pos{Line: 11, Column: 1},
+						End:      hcl.Pos{Line: 11, Column: 28},
+					},
 				},
 			},
 		},
@@ -129,6 +135,9 @@ deny_not_t2_micro contains issue if {
 			if err := rule.Check(runner); err != nil {
 				t.Fatal(err)
 			}
+			for _, issue := range runner.Issues {
+				issue.Message = normalizeDuration(issue.Message)
+			}
 
 			helper.AssertIssues(t, test.want, runner.Issues)
 		})
@@ -178,7 +187,12 @@ deny_not_snake_case contains issue if {
 			want: helper.Issues{
 				{
 					Rule:    &TestRule{},
-					Message: "test failed",
+					Message: "test failed: data.tflint.test_deny_not_snake_case (0s)",
+					Range: hcl.Range{
+						Filename: "main_test.rego",
+						Start:    hcl.Pos{Line: 10, Column: 1},
+						End:      hcl.Pos{Line: 10, Column: 30},
+					},
 				},
 			},
 		},
@@ -217,6 +231,9 @@ deny_not_snake_case contains issue if {
 			if err := rule.Check(runner); err != nil {
 				t.Fatal(err)
 			}
+			for _, issue := range runner.Issues {
+				issue.Message = normalizeDuration(issue.Message)
+			}
 
 			helper.AssertIssues(t, test.want, runner.Issues)
 		})
@@ -0,0 +1,150 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/terraform-linters/tflint-ruleset-opa/opa/lang"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// declareObject declares a resource, data source, ephemeral resource, or module call
+// so that references to it are evaluated as unknown values.
+func (r *testRunner) declareObject(block *hcl.Block) {
+	var root, key, name string
+	switch block.Type {
+	case "resource":
+		// Resources are referenced as <TYPE>.<NAME>
+		root, name = block.Labels[0], block.Labels[1]
+	case "data", "ephemeral":
+		root, key, name = block.Type, block.Labels[0], block.Labels[1]
+	case "module":
+		root, name = block.Type, block.Labels[0]
+	}
+
+	value := cty.DynamicVal
+	if block.Type == "ephemeral" {
+		value = value.WithMarks(ephemeralMark)
+	}
+
+	if _, exists := r.objects[root]; !exists {
+		r.objects[root] = map[string]cty.Value{}
+	}
+	if key == "" {
+		r.objects[root][name] = value
+		return
+	}
+
+	attrs := map[string]cty.Value{}
+	if current, exists := r.objects[root][key]; exists {
+		attrs = current.AsValueMap()
+	}
+	attrs[name] = value
+	r.objects[root][key] = cty.ObjectVal(attrs)
+}
+
+// evalContext returns the context for evaluating the expressions.
+// The visiting is the set of local values being evaluated, used to detect cycles.
+func (r *testRunner) evalContext(visiting map[string]bool, exprs ...hcl.Expression) (*hcl.EvalContext, error) {
+	variables := map[string]cty.Value{}
+	for root, objects := range r.objects {
+		variables[root] = cty.ObjectVal(objects)
+	}
+
+	vars := map[string]cty.Value{}
+	for _, variable := range r.variables {
+		val := variable.Value
+		if val == cty.NilVal {
+			val = variable.Default
+		}
+		if val == cty.NilVal {
+			val = cty.UnknownVal(variable.Type)
+		}
+		if variable.Sensitive {
+			val = val.WithMarks(sensitiveMark)
+		}
+		if variable.Ephemeral {
+			val = val.WithMarks(ephemeralMark)
+		}
+		vars[variable.Name] = val
+	}
+	variables["var"] = cty.ObjectVal(vars)
+
+	// Only referenced local values are evaluated, in the order of dependencies.
+	locals := map[string]cty.Value{}
+	traversals := []hcl.Traversal{}
+	for _, expr := range exprs {
+		traversals = append(traversals, expr.Variables()...)
+	}
+	for _, traversal := range traversals {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		if _, exists := r.locals[attr.Name]; !exists {
+			continue
+		}
+
+		val, err := r.localValue(attr.Name, visiting)
+		if err != nil {
+			return nil, err
+		}
+		locals[attr.Name] = val
+	}
+	variables["local"] = cty.ObjectVal(locals)
+
+	// Iterators are bound when blocks are expanded. Otherwise, they are unknown.
+	variables["count"] = cty.DynamicVal
+	variables["each"] = cty.DynamicVal
+
+	cwd, err := r.GetOriginalwd()
+	if err != nil {
+		return nil, err
+	}
+	// Like TFLint, the paths are relative to the working directory.
+	variables["path"] = cty.ObjectVal(map[string]cty.Value{
+		"module": cty.StringVal(filepath.ToSlash(r.dir)),
+		"root":   cty.StringVal(filepath.ToSlash(r.root.dir)),
+		"cwd":    cty.StringVal(cwd),
+	})
+
+	workspace := os.Getenv("TF_WORKSPACE")
+	if workspace == "" {
+		workspace = "default"
+	}
+	variables["terraform"] = cty.ObjectVal(map[string]cty.Value{
+		"workspace": cty.StringVal(workspace),
+	})
+
+	return &hcl.EvalContext{Variables: variables, Functions: lang.PureFunctions(exprs...)}, nil
+}
+
+func (r *testRunner) localValue(name string, visiting map[string]bool) (cty.Value, error) {
+	if val, exists := r.localValues[name]; exists {
+		return val, nil
+	}
+
+	attr := r.locals[name]
+	if visiting[name] {
+		return cty.NilVal, fmt.Errorf("%s: cycle in local values; local.%s refers to itself", attr.Expr.Range(), name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	ctx, err := r.evalContext(visiting, attr.Expr)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	val, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	r.localValues[name] = val
+	return val, nil
+}
@@ -0,0 +1,342 @@
+package tester
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/dynblock"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// expandedBody is a body whose blocks are expanded by count, for_each, and dynamic blocks.
+// Dynamic blocks are expanded by hcl/ext/dynblock, as Terraform does.
+// Attributes referring to the iterators (count, each, and dynamic block iterators)
+// are evaluated and bound, as TFLint does.
+//
+// Blocks with unknown count or for_each are dropped, as TFLint does.
+type expandedBody struct {
+	original hcl.Body
+	runner   *testRunner
+	// root is true for top-level bodies of config files.
+	// Only resources, data sources, ephemeral resources, and module calls in the root are expanded by count/for_each.
+	root bool
+	// dynamic is true if dynamic blocks in the original body are not expanded yet.
+	// Once expanded by dynblock, nested bodies are expanded by dynblock as well.
+	dynamic   bool
+	iterators map[string]cty.Value
+}
+
+var _ hcl.Body = (*expandedBody)(nil)
+
+var expandableBlockTypes = map[string]bool{
+	"resource":  true,
+	"data":      true,
+	"ephemeral": true,
+	"module":    true,
+}
+
+var metaArgSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "count"},
+		{Name: "for_each"},
+	},
+}
+
+func (b *expandedBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	body, expanded, diags := b.expandDynamic(schema)
+	if diags.HasErrors() {
+		return &hcl.BodyContent{}, diags
+	}
+	content, diags := body.Content(schema)
+	if diags.HasErrors() {
+		return content, diags
+	}
+	return b.expandContent(content, expanded)
+}
+
+func (b *expandedBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	body, expanded, diags := b.expandDynamic(schema)
+	if diags.HasErrors() {
+		return &hcl.BodyContent{}, b.original, diags
+	}
+	content, remain, diags := body.PartialContent(schema)
+	if diags.HasErrors() {
+		return content, remain, diags
+	}
+	remain = &expandedBody{original: remain, runner: b.runner, root: b.root, dynamic: b.dynamic && !expanded, iterators: b.iterators}
+
+	content, diags = b.expandContent(content, expanded)
+	return content, remain, diags
+}
+
+func (b *expandedBody) JustAttributes() (hcl.Attributes, hcl.Diagnostics) {
+	attrs, diags := b.original.JustAttributes()
+	if diags.HasErrors() {
+		return attrs, diags
+	}
+	return b.bindAttributes(attrs)
+}
+
+func (b *expandedBody) MissingItemRange() hcl.Range {
+	return b.original.MissingItemRange()
+}
+
+// expandDynamic returns the body whose dynamic blocks are expanded by dynblock, and whether it is expanded.
+// The for_each and labels of dynamic blocks are evaluated in the context with the iterators.
+// If dynamic blocks are requested explicitly, they are returned as is.
+func (b *expandedBody) expandDynamic(schema *hcl.BodySchema) (hcl.Body, bool, hcl.Diagnostics) {
+	if !b.dynamic {
+		return b.original, false, nil
+	}
+	for _, block := range schema.Blocks {
+		if block.Type == "dynamic" {
+			return b.original, false, nil
+		}
+	}
+
+	ctx, err := b.runner.evalContext(map[string]bool{}, dynamicBlockExprs(b.original)...)
+	if err != nil {
+		return nil, false, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to evaluate an expression",
+			Detail:   err.Error(),
+			Subject:  b.original.MissingItemRange().Ptr(),
+		}}
+	}
+	for name, val := range b.iterators {
+		ctx.Variables[name] = val
+	}
+	return dynblock.Expand(b.original, ctx), true, nil
+}
+
+// dynamicBlockExprs returns the expressions evaluated to expand dynamic blocks in the body.
+// In JSON syntax, dynamic blocks cannot be distinguished without the schema,
+// so all expressions in the body are returned.
+func dynamicBlockExprs(body hcl.Body) []hcl.Expression {
+	exprs := []hcl.Expression{}
+
+	if body, ok := body.(*hclsyntax.Body); ok {
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			block, ok := node.(*hclsyntax.Block)
+			if !ok || block.Type != "dynamic" {
+				return nil
+			}
+			for _, name := range []string{"for_each", "labels"} {
+				if attr, exists := block.Body.Attributes[name]; exists {
+					exprs = append(exprs, attr.Expr)
+				}
+			}
+			return nil
+		})
+		return exprs
+	}
+
+	attrs, _ := body.JustAttributes()
+	for _, attr := range attrs {
+		exprs = append(exprs, attr.Expr)
+	}
+	return exprs
+}
+
+// expandContent expands blocks in the content. If the content is expanded by dynblock,
+// nested bodies are expanded by dynblock as well. Otherwise, they are expanded later.
+func (b *expandedBody) expandContent(content *hcl.BodyContent, expanded bool) (*hcl.BodyContent, hcl.Diagnostics) {
+	attrs, diags := b.bindAttributes(content.Attributes)
+	if diags.HasErrors() {
+		return content, diags
+	}
+
+	ret := &hcl.BodyContent{
+		Attributes:       attrs,
+		Blocks:           hcl.Blocks{},
+		MissingItemRange: content.MissingItemRange,
+	}
+	for _, block := range content.Blocks {
+		// Blocks generated by dynamic blocks with unknown for_each are dropped, as TFLint does.
+		if body, ok := block.Body.(hcldec.UnknownBody); ok && body.Unknown() {
+			continue
+		}
+
+		var blocks hcl.Blocks
+		switch {
+		case expandableBlockTypes[block.Type] && b.root:
+			blocks, diags = b.expandMetaArgs(block)
+		default:
+			blocks = hcl.Blocks{b.child(block, b.iterators, !expanded)}
+		}
+		if diags.HasErrors() {
+			return ret, diags
+		}
+		ret.Blocks = append(ret.Blocks, blocks...)
+	}
+
+	return ret, nil
+}
+
+// expandMetaArgs expands the block by count or for_each.
+func (b *expandedBody) expandMetaArgs(block *hcl.Block) (hcl.Blocks, hcl.Diagnostics) {
+	content, _, diags := block.Body.PartialContent(metaArgSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if attr, exists := content.Attributes["count"]; exists {
+		val, diags := b.evaluate(attr.Expr, b.iterators)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		val, _ = val.Unmark()
+		if !val.IsKnown() {
+			return hcl.Blocks{}, nil
+		}
+		if val.IsNull() {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid count argument",
+				Detail:   `The given "count" argument value is null. An integer is required.`,
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+		val, err := convert.Convert(val, cty.Number)
+		if err != nil {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid count argument",
+				Detail:   fmt.Sprintf(`The given "count" argument value is unsuitable: %s.`, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+		var count int
+		if err := gocty.FromCtyValue(val, &count); err != nil {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid count argument",
+				Detail:   fmt.Sprintf(`The given "count" argument value is unsuitable: %s.`, err),
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+		if count < 0 {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid count argument",
+				Detail:   `The given "count" argument value is unsuitable: must be greater than or equal to zero.`,
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+
+		ret := hcl.Blocks{}
+		for i := 0; i < count; i++ {
+			ret = append(ret, b.child(block, b.withIterator("count", cty.ObjectVal(map[string]cty.Value{
+				"index": cty.NumberIntVal(int64(i)),
+			})), true))
+		}
+		return ret, nil
+	}
+
+	if attr, exists := content.Attributes["for_each"]; exists {
+		val, diags := b.evaluate(attr.Expr, b.iterators)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		val, _ = val.Unmark()
+		if !val.IsWhollyKnown() {
+			return hcl.Blocks{}, nil
+		}
+		if val.IsNull() || !(val.Type().IsMapType() || val.Type().IsObjectType() || val.Type().IsSetType()) {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid for_each argument",
+				Detail:   fmt.Sprintf(`The given "for_each" argument value is unsuitable: the "for_each" argument must be a map, or set of strings, and you have provided a value of type %s.`, val.Type().FriendlyName()),
+				Subject:  attr.Expr.Range().Ptr(),
+			}}
+		}
+
+		ret := hcl.Blocks{}
+		for it := val.ElementIterator(); it.Next(); {
+			key, value := it.Element()
+			if val.Type().IsSetType() {
+				// Keys of sets are the same as the values
+				key = value
+			}
+			ret = append(ret, b.child(block, b.withIterator("each", cty.ObjectVal(map[string]cty.Value{
+				"key":   key,
+				"value": value,
+			})), true))
+		}
+		return ret, nil
+	}
+
+	return hcl.Blocks{b.child(block, b.iterators, true)}, nil
+}
+
+// bindAttributes binds values to the attributes referring to the iterators.
+// Expressions wrapped by dynblock are unwrapped so that they can be handled as the original syntax.
+func (b *expandedBody) bindAttributes(attrs hcl.Attributes) (hcl.Attributes, hcl.Diagnostics) {
+	ret := hcl.Attributes{}
+	for name, attr := range attrs {
+		expr := hcl.UnwrapExpression(attr.Expr)
+		ret[name] = &hcl.Attribute{
+			Name:      attr.Name,
+			Expr:      expr,
+			Range:     attr.Range,
+			NameRange: attr.NameRange,
+		}
+
+		// Dynamic block iterators are hidden from the variables of wrapped expressions.
+		refersIterator := len(attr.Expr.Variables()) != len(expr.Variables())
+		for _, traversal := range attr.Expr.Variables() {
+			if _, exists := b.iterators[traversal.RootName()]; exists {
+				refersIterator = true
+				break
+			}
+		}
+		if !refersIterator {
+			continue
+		}
+
+		val, diags := b.evaluate(attr.Expr, b.iterators)
+		if diags.HasErrors() {
+			return ret, diags
+		}
+		ret[name].Expr = hclext.BindValue(val, expr)
+	}
+	return ret, nil
+}
+
+func (b *expandedBody) evaluate(expr hcl.Expression, iterators map[string]cty.Value) (cty.Value, hcl.Diagnostics) {
+	ctx, err := b.runner.evalContext(map[string]bool{}, expr)
+	if err != nil {
+		return cty.NilVal, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Failed to evaluate an expression",
+			Detail:   err.Error(),
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+	for name, val := range iterators {
+		ctx.Variables[name] = val
+	}
+	return expr.Value(ctx)
+}
+
+func (b *expandedBody) withIterator(name string, val cty.Value) map[string]cty.Value {
+	ret := map[string]cty.Value{name: val}
+	for k, v := range b.iterators {
+		if k == name {
+			continue
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+func (b *expandedBody) child(block *hcl.Block, iterators map[string]cty.Value, dynamic bool) *hcl.Block {
+	ret := *block
+	ret.Body = &expandedBody{original: block.Body, runner: b.runner, dynamic: dynamic, iterators: iterators}
+	return &ret
+}
@@ -1,16 +1,26 @@
 package tester
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
 	"github.com/terraform-linters/tflint-plugin-sdk/terraform/addrs"
 	"github.com/terraform-linters/tflint-plugin-sdk/terraform/lang/marks"
 	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
+	"github.com/terraform-linters/tflint-ruleset-opa/opa/lang"
 	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/gocty"
 )
 
@@ -18,13 +28,73 @@ import (
 // This can be used to inspect Terraform config files written within tests.
 // Since it is different from a real gRPC client, some features are limited.
 type testRunner struct {
-	files     map[string]*hcl.File
-	variables map[string]*variable
+	files       map[string]*hcl.File
+	sources     map[string][]byte
+	variables   map[string]*variable
+	locals      map[string]*hcl.Attribute
+	localValues map[string]cty.Value
+	objects     map[string]map[string]cty.Value
+	config      *config
+	modulePath  addrs.Module
+	originalwd  string
+	// dir is the module directory, used as path.module and the directory for reading files.
+	dir    string
+	issues []*Issue
+	// root is the runner for the root module, used when the root module context is requested.
+	// If the current module is the root module, it is the runner itself.
+	root *testRunner
+}
+
+// Issue is an issue emitted by EmitIssue.
+type Issue struct {
+	Rule    tflint.Rule
+	Message string
+	Range   hcl.Range
+}
+
+// config is a pseudo TFLint config file, loaded from .tflint.hcl.
+type config struct {
+	Rules  []*ruleConfig `hcl:"rule,block"`
+	Remain hcl.Body      `hcl:",remain"`
+}
+
+type ruleConfig struct {
+	Name    string   `hcl:"name,label"`
+	Enabled bool     `hcl:"enabled"`
+	Body    hcl.Body `hcl:",remain"`
+}
+
+// configFileName is the file name of the TFLint config file.
+const configFileName = ".tflint.hcl"
+
+// Inputs are the inputs to the runner other than files.
+type Inputs struct {
+	// Variables are input values of variables, like the -var option.
+	// The values are converted to the declared types.
+	Variables map[string]cty.Value
+	// UnknownVariables are variables that are deliberately unknown.
+	// This simulates values provided at apply time.
+	UnknownVariables []string
+	// ModulePath is the module path being inspected, such as "module.network.module.subnets".
+	// An empty string means the root module.
+	ModulePath string
+	// OriginalWorkingDir is the working directory where TFLint is run.
+	// An empty string means the current directory.
+	OriginalWorkingDir string
+	// RootModule is the files of the root module, used when the root module context is requested.
+	// This can be set only if ModulePath is set. If not set, the root module is empty.
+	RootModule map[string]string
 }
 
 type variable struct {
-	Name      string
-	Default   cty.Value
+	Name string
+	Type cty.Type
+	// Defaults are the default values of optional attributes in the type constraint.
+	Defaults *typeexpr.Defaults
+	Default  cty.Value
+	// Value is the input value from tfvars files or the inputs.
+	// If no value is passed, it is cty.NilVal and the default is used.
+	Value     cty.Value
 	Sensitive bool
 	Ephemeral bool
 	DeclRange hcl.Range
@@ -32,14 +102,40 @@ type variable struct {
 
 var _ tflint.Runner = (*testRunner)(nil)
 
+// NewRunner returns a test runner for the passed files.
 func NewRunner(files map[string]string) (*testRunner, hcl.Diagnostics) {
+	return NewRunnerWithInputs(files, Inputs{})
+}
+
+// NewRunnerWithInputs returns a test runner for the passed files and inputs such as variable values.
+func NewRunnerWithInputs(files map[string]string, inputs Inputs) (*testRunner, hcl.Diagnostics) {
 	runner := &testRunner{
-		files:     map[string]*hcl.File{},
-		variables: map[string]*variable{},
+		files:       map[string]*hcl.File{},
+		sources:     map[string][]byte{},
+		variables:   map[string]*variable{},
+		locals:      map[string]*hcl.Attribute{},
+		localValues: map[string]cty.Value{},
+		objects:     map[string]map[string]cty.Value{},
+		config:      &config{},
+		modulePath:  addrs.Module{},
 	}
 	parser := hclparse.NewParser()
 
-	for name, src := range files {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		src := files[name]
+		runner.sources[filepath.Clean(name)] = []byte(src)
+
+		if !isModuleFile(name) {
+			// Files such as README.md and tfvars files are not module files.
+			// They are kept as raw sources and can be accessed by ReadFile.
+			continue
+		}
 		var file *hcl.File
 		var diags hcl.Diagnostics
 		if strings.HasSuffix(name, ".json") {
@@ -48,11 +144,16 @@ func NewRunner(files map[string]string) (*testRunner, hcl.Diagnostics) {
 			file, diags = parser.ParseHCL([]byte(src), name)
 		}
 		if diags.HasErrors() {
+			if filepath.Ext(name) == "" {
+				// Files like CODEOWNERS are not HCL, so they are kept as raw sources.
+				continue
+			}
 			return runner, diags
 		}
 
 		runner.files[name] = file
 	}
+	runner.dir = moduleDir(names)
 
 	for _, file := range runner.files {
 		content, _, diags := file.Body.PartialContent(configFileSchema)
@@ -63,29 +164,185 @@ func NewRunner(files map[string]string) (*testRunner, hcl.Diagnostics) {
 		for _, block := range content.Blocks {
 			switch block.Type {
 			case "variable":
-				// Only "variable" blocks are interpreted
 				variable, diags := decodeVariableBlock(block)
 				if diags.HasErrors() {
 					return runner, diags
 				}
 				runner.variables[variable.Name] = variable
+			case "locals":
+				attrs, diags := block.Body.JustAttributes()
+				if diags.HasErrors() {
+					return runner, diags
+				}
+				for name, attr := range attrs {
+					runner.locals[name] = attr
+				}
+			case "resource", "data", "ephemeral", "module":
+				// Only the addresses are interpreted. Their attributes are always unknown.
+				runner.declareObject(block)
 			default:
 				continue
 			}
 		}
 	}
 
+	if diags := runner.loadVariableValues(files, inputs); diags.HasErrors() {
+		return runner, diags
+	}
+
+	if src, exists := files[configFileName]; exists {
+		file, diags := parser.ParseHCL([]byte(src), configFileName)
+		if diags.HasErrors() {
+			return runner, diags
+		}
+		if diags := gohcl.DecodeBody(file.Body, nil, runner.config); diags.HasErrors() {
+			return runner, diags
+		}
+	}
+
+	modulePath, diags := parseModulePath(inputs.ModulePath)
+	if diags.HasErrors() {
+		return runner, diags
+	}
+	runner.modulePath = modulePath
+	runner.originalwd = inputs.OriginalWorkingDir
+
+	runner.root = runner
+	if len(modulePath) > 0 {
+		root, diags := NewRunnerWithInputs(inputs.RootModule, Inputs{OriginalWorkingDir: inputs.OriginalWorkingDir})
+		if diags.HasErrors() {
+			return runner, diags
+		}
+		runner.root = root
+	} else if inputs.RootModule != nil {
+		return runner, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid root module",
+			Detail:   "The root module files can be passed only if the module path is set.",
+		}}
+	}
+
 	return runner, nil
 }
 
+// moduleDir returns the module directory, which is the shallowest directory
+// containing Terraform config files, such as "." for main.tf and "dir" for dir/main.tf.
+// If there are multiple such directories, the first one in lexical order is used.
+// If there are no config files, the current directory is assumed.
+func moduleDir(names []string) string {
+	dir := "."
+	depth := -1
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".tf") && !strings.HasSuffix(name, ".tf.json") {
+			continue
+		}
+		d := filepath.Dir(filepath.Clean(name))
+		n := 0
+		if d != "." {
+			n = len(strings.Split(d, string(filepath.Separator)))
+		}
+		if depth == -1 || n < depth || (n == depth && d < dir) {
+			dir = d
+			depth = n
+		}
+	}
+	return dir
+}
+
+// nonModuleFileSuffixes are suffixes of HCL and JSON files that are not module files.
+var nonModuleFileSuffixes = []string{
+	".tfvars", ".tfvars.json",
+	".tftest.hcl", ".tftest.json",
+	".tfmock.hcl", ".tfmock.json",
+	".terraform.lock.hcl",
+	configFileName,
+}
+
+// isModuleFile returns true if the file is parsed as a module file.
+// Like NewRunner has always done, any HCL and JSON files are module files, including files
+// without an extension and files in subdirectories, except for files that are known not to
+// be module files, such as tfvars files and files in the .terraform directory.
+func isModuleFile(name string) bool {
+	name = filepath.Clean(name)
+	if strings.HasPrefix(name, ".terraform"+string(filepath.Separator)) {
+		return false
+	}
+	for _, suffix := range nonModuleFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	switch filepath.Ext(name) {
+	case "", ".tf", ".hcl", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseModulePath parses the module path such as "module.network.module.subnets".
+// An empty string means the root module.
+func parseModulePath(path string) (addrs.Module, hcl.Diagnostics) {
+	if path == "" {
+		return addrs.Module{}, nil
+	}
+
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(path), "module_path", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	ret := addrs.Module{}
+	for i := 0; i < len(traversal); i += 2 {
+		var keyword, name string
+		switch step := traversal[i].(type) {
+		case hcl.TraverseRoot:
+			keyword = step.Name
+		case hcl.TraverseAttr:
+			keyword = step.Name
+		}
+		if i+1 < len(traversal) {
+			if step, ok := traversal[i+1].(hcl.TraverseAttr); ok {
+				name = step.Name
+			}
+		}
+		if keyword != "module" || name == "" {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid module path",
+				Detail:   fmt.Sprintf(`The module path must be like "module.<name>.module.<name>", got "%s".`, path),
+			}}
+		}
+		ret = append(ret, name)
+	}
+	return ret, nil
+}
+
 // GetModuleContent gets a content of the module.
-// dynamic blocks, meta-arguments and overrides are not considered
-func (r *testRunner) GetModuleContent(schema *hclext.BodySchema, _ *tflint.GetModuleContentOption) (*hclext.BodyContent, error) {
+// Blocks are expanded by count, for_each, and dynamic blocks unless the expand mode is none.
+// If the root module context is requested, the content of the root module is returned.
+// Overrides are not considered.
+func (r *testRunner) GetModuleContent(schema *hclext.BodySchema, opts *tflint.GetModuleContentOption) (*hclext.BodyContent, error) {
+	if opts != nil && opts.ModuleCtx == tflint.RootModuleCtxType && r.root != r {
+		return r.root.GetModuleContent(schema, &tflint.GetModuleContentOption{ExpandMode: opts.ExpandMode})
+	}
+
 	content := &hclext.BodyContent{}
 	diags := hcl.Diagnostics{}
 
-	for _, f := range r.files {
-		c, d := hclext.PartialContent(f.Body, schema)
+	names := make([]string, 0, len(r.files))
+	for name := range r.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var body hcl.Body = r.files[name].Body
+		if opts == nil || opts.ExpandMode == tflint.ExpandModeExpand {
+			body = &expandedBody{original: body, runner: r, root: true}
+		}
+
+		c, d := hclext.PartialContent(body, schema)
 		diags = diags.Extend(d)
 		for name, attr := range c.Attributes {
 			content.Attributes[name] = attr
@@ -104,29 +361,21 @@ var ephemeralMark = cty.NewValueMarks(marks.Ephemeral)
 
 // EvaluateExpr returns a value of the passed expression.
 // Not expected to reflect anything other than cty.Value.
-// It is an error to evaluate anything other than a variable.
-// Functions are also not supported.
-func (r *testRunner) EvaluateExpr(expr hcl.Expression, ret interface{}, _ *tflint.EvaluateExprOption) error {
-	variables := map[string]cty.Value{}
-	for _, variable := range r.variables {
-		val := variable.Default
-		if val == cty.NilVal {
-			val = cty.DynamicVal
-		}
-		if variable.Sensitive {
-			val = val.WithMarks(sensitiveMark)
-		}
-		if variable.Ephemeral {
-			val = val.WithMarks(ephemeralMark)
-		}
-		variables[variable.Name] = val
+// Variables, local values, path.*, and terraform.workspace are evaluated.
+// References to resources, data sources, and modules are unknown.
+// Calls of Terraform functions that read the filesystem or depend on the time return unknown values.
+// If the root module context is requested, the expression is evaluated in the root module.
+func (r *testRunner) EvaluateExpr(expr hcl.Expression, ret interface{}, opts *tflint.EvaluateExprOption) error {
+	if opts != nil && opts.ModuleCtx == tflint.RootModuleCtxType && r.root != r {
+		return r.root.EvaluateExpr(expr, ret, nil)
 	}
 
-	val, diags := expr.Value(&hcl.EvalContext{
-		Variables: map[string]cty.Value{
-			"var": cty.ObjectVal(variables),
-		},
-	})
+	ctx, err := r.evalContext(map[string]bool{}, expr)
+	if err != nil {
+		return err
+	}
+
+	val, diags := expr.Value(ctx)
 	if diags.HasErrors() {
 		return diags
 	}
@@ -134,9 +383,13 @@ func (r *testRunner) EvaluateExpr(expr hcl.Expression, ret interface{}, _ *tflin
 	return gocty.FromCtyValue(val, ret)
 }
 
-// GetFile returns the hcl.File object
+// GetFile returns the hcl.File object.
+// Like TFLint, files in the root module are also looked up.
 func (r *testRunner) GetFile(filename string) (*hcl.File, error) {
-	return r.files[filename], nil
+	if file, exists := r.files[filename]; exists {
+		return file, nil
+	}
+	return r.root.files[filename], nil
 }
 
 // GetFiles returns all hcl.File
@@ -144,40 +397,211 @@ func (r *testRunner) GetFiles() (map[string]*hcl.File, error) {
 	return r.files, nil
 }
 
+// ModuleDir returns the module directory. Unlike TFLint, mock files can be placed
+// in multiple directories, so the directory is not derived from GetFiles.
+func (r *testRunner) ModuleDir() string {
+	return r.dir
+}
+
+// ReadFile returns the content of the passed file.
+// Unlike GetFile, this can read any file, not just Terraform config files.
+func (r *testRunner) ReadFile(name string) ([]byte, error) {
+	src, exists := r.sources[filepath.Clean(name)]
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return src, nil
+}
+
+// EvalSymlinks returns the cleaned path, since in-memory files have no symbolic links.
+func (r *testRunner) EvalSymlinks(name string) (string, error) {
+	return filepath.Clean(name), nil
+}
+
+// Glob returns the names of sources matching the pattern, in lexical order.
+// The pattern syntax is the same as filepath.Match.
+func (r *testRunner) Glob(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+
+	ret := []string{}
+	for name := range r.sources {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// Functions returns Terraform functions for evaluating expressions that are not part of the module files,
+// such as the source of terraform.eval. They are the same as the functions used in the module files.
+func (r *testRunner) Functions(exprs ...hcl.Expression) map[string]function.Function {
+	return lang.PureFunctions(exprs...)
+}
+
+// DecodeRuleConfig decodes the rule config in .tflint.hcl into the passed value.
+// If the rule is not configured, nothing is decoded.
 func (r *testRunner) DecodeRuleConfig(name string, ret interface{}) error {
-	panic("Not implemented in test runner")
+	schema := hclext.ImpliedBodySchema(ret)
+
+	for _, rule := range r.config.Rules {
+		if rule.Name != name {
+			continue
+		}
+
+		content, diags := hclext.Content(rule.Body, schema)
+		if diags.HasErrors() {
+			return diags
+		}
+		if diags := hclext.DecodeBody(content, nil, ret); diags.HasErrors() {
+			return diags
+		}
+		return nil
+	}
+
+	return nil
 }
 
+// EmitIssue adds an issue to the runner. Emitted issues are available in Issues.
 func (r *testRunner) EmitIssue(rule tflint.Rule, message string, location hcl.Range) error {
-	panic("Not implemented in test runner")
+	r.issues = append(r.issues, &Issue{Rule: rule, Message: message, Range: location})
+	return nil
 }
 
+// EmitIssueWithFix adds an issue to the runner.
+// Fixes are never applied in the test runner, so the fix function is not invoked.
 func (r *testRunner) EmitIssueWithFix(rule tflint.Rule, message string, location hcl.Range, fixFunc func(f tflint.Fixer) error) error {
-	panic("Not implemented in test runner")
+	return r.EmitIssue(rule, message, location)
 }
 
+// Issues returns issues emitted by EmitIssue.
+func (r *testRunner) Issues() []*Issue {
+	return r.issues
+}
+
+// EnsureNoError runs the passed function if there is no error.
+// Like TFLint, errors for unevaluable, null, unknown, and sensitive values are ignored.
 func (r *testRunner) EnsureNoError(err error, proc func() error) error {
-	panic("Not implemented in test runner")
+	if err == nil {
+		return proc()
+	}
+
+	if errors.Is(err, tflint.ErrUnevaluable) || errors.Is(err, tflint.ErrNullValue) || errors.Is(err, tflint.ErrUnknownValue) || errors.Is(err, tflint.ErrSensitive) {
+		return nil
+	}
+	return err
 }
 
+// GetModulePath returns the module path set by the inputs.
+// By default, it returns the root module.
 func (r *testRunner) GetModulePath() (addrs.Module, error) {
-	panic("Not implemented in test runner")
+	return r.modulePath, nil
 }
 
+// GetOriginalwd returns the original working directory set by the inputs.
+// By default, it returns the current directory.
 func (r *testRunner) GetOriginalwd() (string, error) {
-	panic("Not implemented in test runner")
+	if r.originalwd != "" {
+		return r.originalwd, nil
+	}
+	return os.Getwd()
 }
 
+// GetProviderContent gets the contents of providers based on the schema.
 func (r *testRunner) GetProviderContent(name string, schema *hclext.BodySchema, opts *tflint.GetModuleContentOption) (*hclext.BodyContent, error) {
-	panic("Not implemented in test runner")
+	body, err := r.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{Type: "provider", LabelNames: []string{"name"}, Body: schema},
+		},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &hclext.BodyContent{Blocks: []*hclext.Block{}}
+	for _, provider := range body.Blocks {
+		if provider.Labels[0] != name {
+			continue
+		}
+		content.Blocks = append(content.Blocks, provider)
+	}
+
+	return content, nil
 }
 
+// GetResourceContent gets the contents of resources based on the schema.
 func (r *testRunner) GetResourceContent(name string, schema *hclext.BodySchema, opts *tflint.GetModuleContentOption) (*hclext.BodyContent, error) {
-	panic("Not implemented in test runner")
+	body, err := r.GetModuleContent(&hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{Type: "resource", LabelNames: []string{"type", "name"}, Body: schema},
+		},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &hclext.BodyContent{Blocks: []*hclext.Block{}}
+	for _, resource := range body.Blocks {
+		if resource.Labels[0] != name {
+			continue
+		}
+		content.Blocks = append(content.Blocks, resource)
+	}
+
+	return content, nil
 }
 
+// WalkExpressions traverses expressions in all files by the passed walker.
+// Like TFLint, only top-level attributes are walked in JSON syntax.
 func (r *testRunner) WalkExpressions(walker tflint.ExprWalker) hcl.Diagnostics {
-	panic("Not implemented in test runner")
+	names := make([]string, 0, len(r.files))
+	for name := range r.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	diags := hcl.Diagnostics{}
+	for _, name := range names {
+		file := r.files[name]
+		if body, ok := file.Body.(*hclsyntax.Body); ok {
+			diags = diags.Extend(hclsyntax.Walk(body, &nativeWalker{walker: walker}))
+			continue
+		}
+
+		attrs, jsonDiags := file.Body.JustAttributes()
+		if jsonDiags.HasErrors() {
+			diags = diags.Extend(jsonDiags)
+			continue
+		}
+		for _, attr := range attrs {
+			diags = diags.Extend(walker.Enter(attr.Expr))
+			diags = diags.Extend(walker.Exit(attr.Expr))
+		}
+	}
+
+	return diags
+}
+
+type nativeWalker struct {
+	walker tflint.ExprWalker
+}
+
+func (w *nativeWalker) Enter(node hclsyntax.Node) hcl.Diagnostics {
+	if expr, ok := node.(hcl.Expression); ok {
+		return w.walker.Enter(expr)
+	}
+	return nil
+}
+
+func (w *nativeWalker) Exit(node hclsyntax.Node) hcl.Diagnostics {
+	if expr, ok := node.(hcl.Expression); ok {
+		return w.walker.Exit(expr)
+	}
+	return nil
 }
 
 func decodeVariableBlock(block *hcl.Block) (*variable, hcl.Diagnostics) {
@@ -187,8 +611,11 @@ func decodeVariableBlock(block *hcl.Block) (*variable, hcl.Diagnostics) {
 	}
 
 	content, _, diags := block.Body.PartialContent(&hcl.BodySchema{
-		// Only supports "default", "sensitive", and "ephemeral"
+		// Only supports "type", "default", "sensitive", and "ephemeral"
 		Attributes: []hcl.AttributeSchema{
+			{
+				Name: "type",
+			},
 			{
 				Name: "default",
 			},
@@ -204,11 +631,24 @@ func decodeVariableBlock(block *hcl.Block) (*variable, hcl.Diagnostics) {
 		return v, diags
 	}
 
+	v.Type = cty.DynamicPseudoType
+	if attr, exists := content.Attributes["type"]; exists {
+		ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		if diags.HasErrors() {
+			return v, diags
+		}
+		v.Type = ty
+		v.Defaults = defaults
+	}
+
 	if attr, exists := content.Attributes["default"]; exists {
 		val, diags := attr.Expr.Value(nil)
 		if diags.HasErrors() {
 			return v, diags
 		}
+		if v.Defaults != nil {
+			val = v.Defaults.Apply(val)
+		}
 
 		v.Default = val
 	}
@@ -235,5 +675,24 @@ var configFileSchema = &hcl.BodySchema{
 			Type:       "variable",
 			LabelNames: []string{"name"},
 		},
+		{
+			Type: "locals",
+		},
+		{
+			Type:       "resource",
+			LabelNames: []string{"type", "name"},
+		},
+		{
+			Type:       "data",
+			LabelNames: []string{"type", "name"},
+		},
+		{
+			Type:       "ephemeral",
+			LabelNames: []string{"type", "name"},
+		},
+		{
+			Type:       "module",
+			LabelNames: []string{"name"},
+		},
 	},
 }
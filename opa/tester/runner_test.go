@@ -2,6 +2,11 @@ package tester
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -9,6 +14,8 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/terraform-linters/tflint-plugin-sdk/hclext"
+	"github.com/terraform-linters/tflint-plugin-sdk/terraform/addrs"
+	"github.com/terraform-linters/tflint-plugin-sdk/tflint"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -191,6 +198,344 @@ func TestGetModuleContent_json(t *testing.T) {
 	}
 }
 
+func TestGetModuleContent_moduleCtx(t *testing.T) {
+	files := map[string]string{
+		"main.tf": `
+variable "region" {
+	default = "us-east-1"
+}
+
+provider "aws" {
+	region = var.region
+}`,
+	}
+	rootFiles := map[string]string{
+		"root.tf": `
+variable "region" {
+	default = "us-west-2"
+}
+
+provider "aws" {
+	region = var.region
+}`,
+	}
+
+	tests := []struct {
+		name   string
+		inputs Inputs
+		ctx    tflint.ModuleCtxType
+		want   string
+	}{
+		{
+			name:   "self module",
+			inputs: Inputs{ModulePath: "module.child", RootModule: rootFiles},
+			ctx:    tflint.SelfModuleCtxType,
+			want:   `main.tf: cty.StringVal("us-east-1")`,
+		},
+		{
+			name:   "root module",
+			inputs: Inputs{ModulePath: "module.child", RootModule: rootFiles},
+			ctx:    tflint.RootModuleCtxType,
+			want:   `root.tf: cty.StringVal("us-west-2")`,
+		},
+		{
+			name:   "root module in the root",
+			inputs: Inputs{},
+			ctx:    tflint.RootModuleCtxType,
+			want:   `main.tf: cty.StringVal("us-east-1")`,
+		},
+	}
+
+	schema := &hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type:       "provider",
+				LabelNames: []string{"name"},
+				Body:       &hclext.BodySchema{Attributes: []hclext.AttributeSchema{{Name: "region"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := NewRunnerWithInputs(files, test.inputs)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			content, err := runner.GetModuleContent(schema, &tflint.GetModuleContentOption{ModuleCtx: test.ctx})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(content.Blocks) != 1 {
+				t.Fatalf("got %d blocks, but 1 block is expected", len(content.Blocks))
+			}
+
+			attr := content.Blocks[0].Body.Attributes["region"]
+			var val cty.Value
+			if err := runner.EvaluateExpr(attr.Expr, &val, &tflint.EvaluateExprOption{ModuleCtx: test.ctx}); err != nil {
+				t.Fatal(err)
+			}
+			got := fmt.Sprintf("%s: %s", attr.Expr.Range().Filename, val.GoString())
+			if got != test.want {
+				t.Errorf("want: %s, got: %s", test.want, got)
+			}
+
+			if file, err := runner.GetFile(attr.Expr.Range().Filename); err != nil || file == nil {
+				t.Errorf("file should be found, but got %v", err)
+			}
+		})
+	}
+
+	if _, diags := NewRunnerWithInputs(files, Inputs{RootModule: rootFiles}); !diags.HasErrors() {
+		t.Error("root module files without the module path should be an error")
+	}
+}
+
+func TestGetModuleContent_expand(t *testing.T) {
+	schema := &hclext.BodySchema{
+		Blocks: []hclext.BlockSchema{
+			{
+				Type:       "resource",
+				LabelNames: []string{"type", "name"},
+				Body: &hclext.BodySchema{
+					Attributes: []hclext.AttributeSchema{{Name: "name"}},
+					Blocks: []hclext.BlockSchema{
+						{
+							Type: "setting",
+							Body: &hclext.BodySchema{Attributes: []hclext.AttributeSchema{{Name: "value"}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		option *tflint.GetModuleContentOption
+		want   []string
+		err    string
+	}{
+		{
+			name: "count",
+			config: `
+resource "aws_instance" "main" {
+	count = 2
+	name  = "web-${count.index}"
+}`,
+			want: []string{`name=cty.StringVal("web-0")`, `name=cty.StringVal("web-1")`},
+		},
+		{
+			name: "count with file function",
+			config: `
+resource "aws_instance" "main" {
+	count = 2
+	name  = "${file("name.txt")}-${count.index}"
+}`,
+			want: []string{
+				`name=cty.UnknownVal(cty.String).RefineNotNull()`,
+				`name=cty.UnknownVal(cty.String).RefineNotNull()`,
+			},
+		},
+		{
+			name: "count = 0",
+			config: `
+resource "aws_instance" "main" {
+	count = 0
+	name  = "web"
+}`,
+			want: []string{},
+		},
+		{
+			name: "for_each map",
+			config: `
+variable "instances" {
+	default = { web = "t2.micro", db = "t2.large" }
+}
+
+resource "aws_instance" "main" {
+	for_each = var.instances
+	name     = "${each.key}:${each.value}"
+}`,
+			want: []string{`name=cty.StringVal("db:t2.large")`, `name=cty.StringVal("web:t2.micro")`},
+		},
+		{
+			name: "for_each set",
+			config: `
+resource "aws_instance" "main" {
+	for_each = toset(["web"])
+	name     = each.key
+}`,
+			want: []string{`name=cty.StringVal("web")`},
+		},
+		{
+			name: "negative count",
+			config: `
+resource "aws_instance" "main" {
+	count = -1
+	name  = "web"
+}`,
+			err: `main.tf:3,10-12: Invalid count argument; The given "count" argument value is unsuitable: must be greater than or equal to zero.`,
+		},
+		{
+			name: "unknown count",
+			config: `
+variable "count" {}
+
+resource "aws_instance" "main" {
+	count = var.count
+	name  = "web"
+}`,
+			want: []string{},
+		},
+		{
+			name: "dynamic blocks",
+			config: `
+locals {
+	settings = ["a", "b"]
+}
+
+resource "aws_instance" "main" {
+	name = "web"
+
+	dynamic "setting" {
+		for_each = local.settings
+		content {
+			value = setting.value
+		}
+	}
+}`,
+			want: []string{`name=cty.StringVal("web") setting.value=cty.StringVal("a") setting.value=cty.StringVal("b")`},
+		},
+		{
+			name: "dynamic blocks with iterator",
+			config: `
+resource "aws_instance" "main" {
+	count = 1
+	name  = "web"
+
+	dynamic "setting" {
+		for_each = ["a"]
+		iterator = it
+		content {
+			value = "${it.value}-${count.index}"
+		}
+	}
+}`,
+			want: []string{`name=cty.StringVal("web") setting.value=cty.StringVal("a-0")`},
+		},
+		{
+			name: "dynamic blocks with for_each iterator",
+			config: `
+resource "aws_instance" "main" {
+	for_each = { web = ["a", "b"] }
+	name     = each.key
+
+	dynamic "setting" {
+		for_each = each.value
+		content {
+			value = "${each.key}-${setting.value}"
+		}
+	}
+}`,
+			want: []string{`name=cty.StringVal("web") setting.value=cty.StringVal("web-a") setting.value=cty.StringVal("web-b")`},
+		},
+		{
+			name: "invalid dynamic for_each",
+			config: `
+resource "aws_instance" "main" {
+	name = "web"
+
+	dynamic "setting" {
+		for_each = 1
+		content {
+			value = setting.value
+		}
+	}
+}`,
+			err: "main.tf:6,14-15: Invalid dynamic for_each value; Cannot use a number value in for_each. An iterable collection is required.",
+		},
+		{
+			name: "unknown dynamic blocks",
+			config: `
+variable "settings" {}
+
+resource "aws_instance" "main" {
+	name = "web"
+
+	dynamic "setting" {
+		for_each = var.settings
+		content {
+			value = setting.value
+		}
+	}
+}`,
+			want: []string{`name=cty.StringVal("web")`},
+		},
+		{
+			name: "expand mode none",
+			config: `
+resource "aws_instance" "main" {
+	count = 0
+	name  = count.index
+
+	dynamic "setting" {
+		for_each = ["a"]
+		content {
+			value = setting.value
+		}
+	}
+}`,
+			option: &tflint.GetModuleContentOption{ExpandMode: tflint.ExpandModeNone},
+			want:   []string{`name=cty.DynamicVal`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := NewRunner(map[string]string{"main.tf": test.config})
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			content, err := runner.GetModuleContent(schema, test.option)
+			if err != nil {
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			evaluate := func(attr *hclext.Attribute) string {
+				var val cty.Value
+				if err := runner.EvaluateExpr(attr.Expr, &val, nil); err != nil {
+					t.Fatal(err)
+				}
+				return fmt.Sprintf("%s=%s", attr.Name, val.GoString())
+			}
+
+			got := []string{}
+			for _, resource := range content.Blocks {
+				values := []string{evaluate(resource.Body.Attributes["name"])}
+				for _, setting := range resource.Body.Blocks {
+					values = append(values, "setting."+evaluate(setting.Body.Attributes["value"]))
+				}
+				got = append(got, strings.Join(values, " "))
+			}
+			sort.Strings(got)
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
 func TestEvaluateExpr(t *testing.T) {
 	parse := func(src string) hcl.Expression {
 		expr, diags := hclsyntax.ParseExpression([]byte(src), "main.tf", hcl.InitialPos)
@@ -205,7 +550,7 @@ func TestEvaluateExpr(t *testing.T) {
 		config string
 		expr   hcl.Expression
 		want   string
-		err    error
+		err    string
 	}{
 		{
 			name: "literal",
@@ -247,6 +592,80 @@ variable "instance_type" {
 			expr: parse("var.instance_type"),
 			want: `cty.StringVal("t2.micro").Mark(marks.Ephemeral)`,
 		},
+		{
+			name: "local value",
+			config: `
+locals {
+	instance_type = "t2.micro"
+}`,
+			expr: parse("local.instance_type"),
+			want: `cty.StringVal("t2.micro")`,
+		},
+		{
+			name: "local value depends on other values",
+			config: `
+variable "env" {
+	default = "prod"
+}
+
+locals {
+	tags = merge(local.common_tags, { Env = var.env })
+	common_tags = { Owner = "team" }
+}`,
+			expr: parse("local.tags"),
+			want: `cty.ObjectVal(map[string]cty.Value{"Env":cty.StringVal("prod"), "Owner":cty.StringVal("team")})`,
+		},
+		{
+			name: "functions",
+			config: `
+variable "name" {
+	default = "web"
+}`,
+			expr: parse(`format("%s-%s", upper(var.name), terraform.workspace)`),
+			want: `cty.StringVal("WEB-default")`,
+		},
+		{
+			name: "file function",
+			expr: parse(`file("main.tf")`),
+			want: `cty.DynamicVal`,
+		},
+		{
+			name: "templatefile function in local value",
+			config: `
+locals {
+	policy = templatefile("policy.tftpl", {})
+}`,
+			expr: parse("local.policy"),
+			want: `cty.DynamicVal`,
+		},
+		{
+			name: "unknown function",
+			expr: parse(`nosuchfunc("main.tf")`),
+			err:  `main.tf:1,1-11: Call to unknown function; There is no function named "nosuchfunc".`,
+		},
+		{
+			name: "path",
+			expr: parse("path.module"),
+			want: `cty.StringVal(".")`,
+		},
+		{
+			name:   "resource reference",
+			config: `resource "aws_instance" "main" {}`,
+			expr:   parse("aws_instance.main.id"),
+			want:   `cty.DynamicVal`,
+		},
+		{
+			name:   "data source reference",
+			config: `data "aws_ami" "main" {}`,
+			expr:   parse("data.aws_ami.main.id"),
+			want:   `cty.DynamicVal`,
+		},
+		{
+			name:   "module reference",
+			config: `module "vpc" {}`,
+			expr:   parse("module.vpc.id"),
+			want:   `cty.DynamicVal`,
+		},
 	}
 
 	for _, test := range tests {
@@ -259,12 +678,12 @@ variable "instance_type" {
 			var got cty.Value
 			err := runner.EvaluateExpr(test.expr, &got, nil)
 			if err != nil {
-				if !errors.Is(err, test.err) {
-					t.Fatal(err)
+				if err.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, err.Error())
 				}
 				return
 			}
-			if test.err != nil {
+			if test.err != "" {
 				t.Fatal("should return an error, but it does not")
 			}
 
@@ -274,3 +693,599 @@ variable "instance_type" {
 		})
 	}
 }
+
+func TestEvaluateExpr_path(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		inputs Inputs
+		want   map[string]string
+	}{
+		{
+			name:  "root module",
+			files: map[string]string{"main.tf": ""},
+			want:  map[string]string{"path.module": ".", "path.root": "."},
+		},
+		{
+			name:  "root module in directory",
+			files: map[string]string{"dir/main.tf": ""},
+			want:  map[string]string{"path.module": "dir", "path.root": "dir"},
+		},
+		{
+			name:   "child module",
+			files:  map[string]string{"modules/vpc/main.tf": ""},
+			inputs: Inputs{ModulePath: "module.vpc", RootModule: map[string]string{"main.tf": ""}},
+			want:   map[string]string{"path.module": "modules/vpc", "path.root": "."},
+		},
+		{
+			name:   "child module without root module",
+			files:  map[string]string{"modules/vpc/main.tf": ""},
+			inputs: Inputs{ModulePath: "module.vpc"},
+			want:   map[string]string{"path.module": "modules/vpc", "path.root": "."},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := NewRunnerWithInputs(test.files, test.inputs)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			got := map[string]string{}
+			for name := range test.want {
+				expr, diags := hclsyntax.ParseExpression([]byte(name), "main.tf", hcl.InitialPos)
+				if diags.HasErrors() {
+					t.Fatal(diags)
+				}
+				var val string
+				if err := runner.EvaluateExpr(expr, &val, nil); err != nil {
+					t.Fatal(err)
+				}
+				got[name] = val
+			}
+
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestEvaluateExpr_variableValues(t *testing.T) {
+	config := `
+variable "instance_type" {
+	default = "t2.micro"
+}
+
+variable "ports" {
+	type    = list(number)
+	default = []
+}
+
+variable "password" {
+	default   = "default"
+	sensitive = true
+}
+
+variable "settings" {
+	type = object({
+		enabled = optional(bool, true)
+		name    = optional(string, "x")
+	})
+	default = {}
+}`
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		inputs Inputs
+		expr   string
+		want   string
+		err    string
+	}{
+		{
+			name:  "default",
+			files: map[string]string{},
+			expr:  "var.instance_type",
+			want:  `cty.StringVal("t2.micro")`,
+		},
+		{
+			name:  "optional attribute defaults",
+			files: map[string]string{},
+			expr:  "var.settings",
+			want:  `cty.ObjectVal(map[string]cty.Value{"enabled":cty.True, "name":cty.StringVal("x")})`,
+		},
+		{
+			name:   "input variables",
+			files:  map[string]string{},
+			inputs: Inputs{Variables: map[string]cty.Value{"instance_type": cty.StringVal("t3.micro")}},
+			expr:   "var.instance_type",
+			want:   `cty.StringVal("t3.micro")`,
+		},
+		{
+			name:   "input variables with complex type",
+			files:  map[string]string{},
+			inputs: Inputs{Variables: map[string]cty.Value{"ports": cty.TupleVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)})}},
+			expr:   "var.ports",
+			want:   `cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)})`,
+		},
+		{
+			name:   "input variables with optional attributes",
+			files:  map[string]string{},
+			inputs: Inputs{Variables: map[string]cty.Value{"settings": cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("y")})}},
+			expr:   "var.settings",
+			want:   `cty.ObjectVal(map[string]cty.Value{"enabled":cty.True, "name":cty.StringVal("y")})`,
+		},
+		{
+			name:  "terraform.tfvars",
+			files: map[string]string{"terraform.tfvars": `instance_type = "t3.large"`},
+			expr:  "var.instance_type",
+			want:  `cty.StringVal("t3.large")`,
+		},
+		{
+			name:   "input variables take precedence over tfvars",
+			files:  map[string]string{"terraform.tfvars": `instance_type = "t3.large"`},
+			inputs: Inputs{Variables: map[string]cty.Value{"instance_type": cty.StringVal("t3.micro")}},
+			expr:   "var.instance_type",
+			want:   `cty.StringVal("t3.micro")`,
+		},
+		{
+			name:   "undeclared input variable",
+			files:  map[string]string{},
+			inputs: Inputs{Variables: map[string]cty.Value{"undeclared": cty.StringVal("foo")}},
+			err:    `Value for undeclared variable`,
+		},
+		{
+			name: "auto.tfvars",
+			files: map[string]string{
+				"terraform.tfvars":      `instance_type = "t3.large"`,
+				"a.auto.tfvars":         `instance_type = "m5.large"`,
+				"b.auto.tfvars.json":    `{"instance_type": "m5.xlarge"}`,
+				"modules/a.auto.tfvars": `instance_type = "c5.large"`,
+			},
+			expr: "var.instance_type",
+			want: `cty.StringVal("m5.xlarge")`,
+		},
+		{
+			name:  "type conversion",
+			files: map[string]string{"terraform.tfvars": `ports = ["80"]`},
+			expr:  "var.ports",
+			want:  `cty.ListVal([]cty.Value{cty.NumberIntVal(80)})`,
+		},
+		{
+			name:  "sensitive",
+			files: map[string]string{"terraform.tfvars": `password = "secret"`},
+			expr:  "var.password",
+			want:  `cty.StringVal("secret").Mark(marks.Sensitive)`,
+		},
+		{
+			name:   "unknown",
+			files:  map[string]string{"terraform.tfvars": `instance_type = "t3.large"`},
+			inputs: Inputs{UnknownVariables: []string{"instance_type", "ports"}},
+			expr:   `[var.instance_type, var.ports]`,
+			want:   `cty.TupleVal([]cty.Value{cty.DynamicVal, cty.UnknownVal(cty.List(cty.Number))})`,
+		},
+		{
+			name:   "undeclared unknown variable",
+			files:  map[string]string{},
+			inputs: Inputs{UnknownVariables: []string{"undeclared"}},
+			err:    `Undeclared unknown variable`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.files["main.tf"] = config
+			runner, diags := NewRunnerWithInputs(test.files, test.inputs)
+			if diags.HasErrors() {
+				if test.err == "" {
+					t.Fatal(diags)
+				}
+				if !strings.Contains(diags.Error(), test.err) {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, diags.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatalf("expect an error, but got nothing")
+			}
+
+			expr, diags := hclsyntax.ParseExpression([]byte(test.expr), "main.tf", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			var got cty.Value
+			if err := runner.EvaluateExpr(expr, &got, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.want != got.GoString() {
+				t.Fatalf("want: %s, got: %s", test.want, got.GoString())
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{
+		"main.tf":   "",
+		"README.md": "# README",
+		filepath.Join("modules", "vpc", "main.tf"): `resource "aws_vpc" "main" {}`,
+	})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	got, err := runner.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# README" {
+		t.Errorf("want: # README, got: %s", got)
+	}
+
+	_, err = runner.ReadFile("CODEOWNERS")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("should return fs.ErrNotExist, but got %v", err)
+	}
+
+	files, err := runner.GetFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := files["README.md"]; exists {
+		t.Error("README.md should not be parsed as a config file")
+	}
+	if _, exists := files[filepath.Join("modules", "vpc", "main.tf")]; !exists {
+		t.Error("modules/vpc/main.tf should be parsed as a module file")
+	}
+
+	got, err = runner.ReadFile(filepath.Join("modules", "vpc", "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `resource "aws_vpc" "main" {}` {
+		t.Errorf("want: the raw source, got: %s", got)
+	}
+}
+
+func TestNewRunner_files(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+		dir   string
+		err   string
+	}{
+		{
+			name: "known files",
+			files: map[string]string{
+				"main.tf":                 "",
+				"main.tf.json":            "{}",
+				"terraform.tfvars":        "",
+				"terraform.tfvars.json":   "{}",
+				".tflint.hcl":             "",
+				".terraform.lock.hcl":     "",
+				"main.tftest.hcl":         "",
+				"tests/main.tftest.hcl":   "",
+				".terraform/modules.json": "{}",
+				"README.md":               "# README",
+				"CODEOWNERS":              "* @terraform-linters/maintainers",
+				"LICENSE":                 "",
+			},
+			want: []string{"LICENSE", "main.tf", "main.tf.json"},
+			dir:  ".",
+		},
+		{
+			name: "config files in multiple directories",
+			files: map[string]string{
+				filepath.Join("b", "main.tf"): "",
+				filepath.Join("a", "main.tf"): "",
+			},
+			want: []string{filepath.Join("a", "main.tf"), filepath.Join("b", "main.tf")},
+			dir:  "a",
+		},
+		{
+			name: "config files in subdirectories",
+			files: map[string]string{
+				filepath.Join("modules", "vpc", "main.tf"): "",
+				filepath.Join("dir", "main.tf"):            "",
+			},
+			want: []string{filepath.Join("dir", "main.tf"), filepath.Join("modules", "vpc", "main.tf")},
+			dir:  "dir",
+		},
+		{
+			name:  "HCL file without extension",
+			files: map[string]string{"main": `resource "aws_instance" "main" {}`},
+			want:  []string{"main"},
+			dir:   ".",
+		},
+		{
+			name:  "HCL file",
+			files: map[string]string{"main.hcl": `instance_type = "t2.micro"`},
+			want:  []string{"main.hcl"},
+			dir:   ".",
+		},
+		{
+			name:  "JSON file",
+			files: map[string]string{"main.json": "{}"},
+			want:  []string{"main.json"},
+			dir:   ".",
+		},
+		{
+			name:  "invalid HCL file",
+			files: map[string]string{"main.hcl": `instance_type = `},
+			err:   "main.hcl:1,17-17: Missing expression; Expected the start of an expression, but found the end of the file.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := NewRunner(test.files)
+			if diags.HasErrors() {
+				if diags.Error() != test.err {
+					t.Fatalf(`expect "%s", but got "%s"`, test.err, diags.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			files, err := runner.GetFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(files))
+			for name := range files {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+			if runner.ModuleDir() != test.dir {
+				t.Errorf("want: %s, got: %s", test.dir, runner.ModuleDir())
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{
+		"main.tf":                           "",
+		"prod.tfvars":                       "",
+		"dev.auto.tfvars":                   "",
+		filepath.Join("envs", "stg.tfvars"): "",
+	})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	got, err := runner.Glob("*.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"dev.auto.tfvars", "prod.tfvars"}, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGetModulePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs Inputs
+		want   addrs.Module
+		err    bool
+	}{
+		{
+			name:   "default",
+			inputs: Inputs{},
+			want:   addrs.Module{},
+		},
+		{
+			name:   "child module",
+			inputs: Inputs{ModulePath: "module.network.module.subnets"},
+			want:   addrs.Module{"network", "subnets"},
+		},
+		{
+			name:   "invalid path",
+			inputs: Inputs{ModulePath: "network.subnets"},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner, diags := NewRunnerWithInputs(map[string]string{}, test.inputs)
+			if diags.HasErrors() {
+				if !test.err {
+					t.Fatal(diags)
+				}
+				return
+			}
+			if test.err {
+				t.Fatal("should return an error, but it does not")
+			}
+
+			got, err := runner.GetModulePath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestGetOriginalwd(t *testing.T) {
+	runner, diags := NewRunnerWithInputs(map[string]string{}, Inputs{OriginalWorkingDir: "/work"})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	got, err := runner.GetOriginalwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/work" {
+		t.Errorf("want: /work, got: %s", got)
+	}
+
+	if _, err := runner.ReadFile("__original_working_dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("inputs should not be files, but got %v", err)
+	}
+}
+
+func TestGetResourceContent(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{"main.tf": `
+resource "aws_instance" "main" {
+	instance_type = "t2.micro"
+}
+
+resource "aws_s3_bucket" "main" {}`})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	got, err := runner.GetResourceContent("aws_instance", &hclext.BodySchema{
+		Attributes: []hclext.AttributeSchema{{Name: "instance_type"}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Blocks) != 1 {
+		t.Fatalf("want 1 block, got %d", len(got.Blocks))
+	}
+	if diff := cmp.Diff([]string{"aws_instance", "main"}, got.Blocks[0].Labels); diff != "" {
+		t.Error(diff)
+	}
+	if _, exists := got.Blocks[0].Body.Attributes["instance_type"]; !exists {
+		t.Error("instance_type should be retrieved")
+	}
+}
+
+type testWalker struct {
+	exprs []string
+}
+
+func (w *testWalker) Enter(expr hcl.Expression) hcl.Diagnostics {
+	w.exprs = append(w.exprs, fmt.Sprintf("%T", expr))
+	return nil
+}
+
+func (w *testWalker) Exit(hcl.Expression) hcl.Diagnostics {
+	return nil
+}
+
+func TestWalkExpressions(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{
+		"main.tf":      `foo = [var.bar]`,
+		"main.tf.json": `{"baz": "qux"}`,
+	})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	walker := &testWalker{}
+	if diags := runner.WalkExpressions(walker); diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	want := []string{"*hclsyntax.TupleConsExpr", "*hclsyntax.ScopeTraversalExpr", "*json.expression"}
+	if diff := cmp.Diff(want, walker.exprs); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDecodeRuleConfig(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{
+		"main.tf": "",
+		".tflint.hcl": `
+plugin "opa" {
+	enabled = true
+}
+
+rule "opa_test" {
+	enabled = true
+	name    = "foo"
+}`,
+	})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	type ruleConfig struct {
+		Name string `hclext:"name,optional"`
+	}
+
+	got := &ruleConfig{}
+	if err := runner.DecodeRuleConfig("opa_test", got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "foo" {
+		t.Errorf("want: foo, got: %s", got.Name)
+	}
+
+	got = &ruleConfig{}
+	if err := runner.DecodeRuleConfig("opa_unknown", got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "" {
+		t.Errorf("want: empty, got: %s", got.Name)
+	}
+}
+
+func TestEmitIssue(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{"main.tf": ""})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	rng := hcl.Range{Filename: "main.tf", Start: hcl.InitialPos, End: hcl.InitialPos}
+	if err := runner.EmitIssue(nil, "issue", rng); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.EmitIssueWithFix(nil, "fixable issue", rng, func(tflint.Fixer) error {
+		t.Fatal("fix should not be invoked")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Issue{
+		{Message: "issue", Range: rng},
+		{Message: "fixable issue", Range: rng},
+	}
+	if diff := cmp.Diff(want, runner.Issues()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEnsureNoError(t *testing.T) {
+	runner, diags := NewRunner(map[string]string{"main.tf": ""})
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+
+	called := false
+	if err := runner.EnsureNoError(nil, func() error { called = true; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("function should be called if there is no error")
+	}
+
+	if err := runner.EnsureNoError(tflint.ErrUnknownValue, func() error { return errors.New("called") }); err != nil {
+		t.Errorf("unknown value error should be ignored, but got %v", err)
+	}
+
+	want := errors.New("failed")
+	if err := runner.EnsureNoError(want, func() error { return nil }); !errors.Is(err, want) {
+		t.Errorf("want: %v, got: %v", want, err)
+	}
+}
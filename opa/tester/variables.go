@@ -0,0 +1,128 @@
+package tester
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// loadVariableValues sets input values to the declared variables.
+// Like Terraform, values are loaded in the following order, with later sources
+// taking precedence over earlier ones:
+//
+//   - terraform.tfvars and terraform.tfvars.json
+//   - *.auto.tfvars and *.auto.tfvars.json, in lexical order of the filenames
+//   - Variables in the inputs
+//
+// Variables listed in UnknownVariables are always unknown.
+// Values for undeclared variables in tfvars files are ignored.
+func (r *testRunner) loadVariableValues(files map[string]string, inputs Inputs) hcl.Diagnostics {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var varFiles, autoVarFiles []string
+	for _, name := range names {
+		if filepath.Dir(filepath.Clean(name)) != r.dir {
+			// Only files in the module directory are loaded automatically
+			continue
+		}
+		switch base := filepath.Base(name); {
+		case base == "terraform.tfvars" || base == "terraform.tfvars.json":
+			varFiles = append(varFiles, name)
+		case strings.HasSuffix(base, ".auto.tfvars") || strings.HasSuffix(base, ".auto.tfvars.json"):
+			autoVarFiles = append(autoVarFiles, name)
+		}
+	}
+
+	parser := hclparse.NewParser()
+	for _, name := range append(varFiles, autoVarFiles...) {
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(name, ".json") {
+			file, diags = parser.ParseJSON([]byte(files[name]), name)
+		} else {
+			file, diags = parser.ParseHCL([]byte(files[name]), name)
+		}
+		if diags.HasErrors() {
+			return diags
+		}
+
+		attrs, diags := file.Body.JustAttributes()
+		if diags.HasErrors() {
+			return diags
+		}
+		for _, attr := range attrs {
+			variable, exists := r.variables[attr.Name]
+			if !exists {
+				continue
+			}
+
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return diags
+			}
+			if diags := r.setVariableValue(variable, val, attr.Expr.Range()); diags.HasErrors() {
+				return diags
+			}
+		}
+	}
+
+	inputNames := make([]string, 0, len(inputs.Variables))
+	for name := range inputs.Variables {
+		inputNames = append(inputNames, name)
+	}
+	sort.Strings(inputNames)
+	for _, name := range inputNames {
+		variable, exists := r.variables[name]
+		if !exists {
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Value for undeclared variable",
+				Detail:   fmt.Sprintf(`The variable "%s" in the inputs is not declared.`, name),
+			}}
+		}
+		if diags := r.setVariableValue(variable, inputs.Variables[name], hcl.Range{}); diags.HasErrors() {
+			return diags
+		}
+	}
+
+	for _, name := range inputs.UnknownVariables {
+		variable, exists := r.variables[name]
+		if !exists {
+			return hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Undeclared unknown variable",
+				Detail:   fmt.Sprintf(`The unknown variable "%s" in the inputs is not declared.`, name),
+			}}
+		}
+		variable.Value = cty.UnknownVal(variable.Type)
+	}
+
+	return nil
+}
+
+func (r *testRunner) setVariableValue(variable *variable, val cty.Value, rng hcl.Range) hcl.Diagnostics {
+	if variable.Defaults != nil {
+		val = variable.Defaults.Apply(val)
+	}
+	val, err := convert.Convert(val, variable.Type)
+	if err != nil {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid value for input variable",
+			Detail:   fmt.Sprintf(`The given value is not suitable for var.%s declared at %s: %s.`, variable.Name, variable.DeclRange, err),
+			Subject:  &rng,
+		}}
+	}
+	variable.Value = val
+	return nil
+}